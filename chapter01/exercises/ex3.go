@@ -22,67 +22,686 @@
 			Use [32]byte as the key instead of strings, and always use the hash value
 			Current fmt.Sprint of hash key makes us use 64 bytes for longer strings
 			May also consider a 128 bit hash; doubles the collision probability, but still small
-		Deliberate choice to use an unbuffered channel, channel consumer is much faster than file i/o
+		The channel into the counter used to be unbuffered, on the grounds that the consumer is much
+		faster than file i/o. Measured (BenchmarkLineBuffer, 8 files of 50000 lines, 1 CPU), that
+		doesn't hold once the files are in the page cache: every line then costs a handoff between
+		goroutines. Unbuffered 19.6 MB/s, 64 lines 28.3 MB/s, 1024 lines 29.7 MB/s. The channel now
+		holds defaultLineBuffer lines, WithLineBuffer changes that; 0 is unbuffered again.
+		Collision check mode (checkCollisions) keeps the first full line per key and warns when a
+		different line lands on the same key. Counts are still merged; the warning just makes it visible.
+		Short lines are kept as raw keys, so many short distinct lines can still blow up the map.
+		maxKeyBytes caps the estimated key footprint (sum of distinct key lengths); once it is crossed
+		the counter switches to always-hash mode. The switch costs one pass over the map rehashing every
+		raw key into a new map, i.e. O(distinct keys) hashing and a transient second copy of the map.
+		Lines already in flight as raw keys are hashed as they arrive, so counts stay exact.
 **/
 
 package exercises
 
 import (
 	"bufio"
-    "crypto/sha256"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"maps"
 	"os"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/unicode/norm"
 )
 
 type rawLineData struct {
 	lineText string
 	fileName string
-	lineNum int
+	lineNum  int
+	fullText string // only set when checking for collisions
+	display  string // only set when it differs from the key, see displayText
+	offset   int64  // only set when withOffsets is
+	casing   string // the line before case folding, only set when casingBreakdown is
 }
 
 type lineData struct {
 	locations map[string][]int
-	count int
+	offsets   map[string][]int64 // byte offsets matching locations, only kept when withOffsets is set
+	count     int
+	truncated bool           // locations stopped at locationsCap, count is still exact
+	seq       int            // order in which the distinct lines were first seen
+	casings   map[string]int // occurrences of each casing folded into the key, see casingBreakdown
+	// First full line seen for this key, only kept when checking for collisions
+	firstText string
+	firstFile string
+	firstLine int
+	// How to report the first line, only kept when that differs from the key
+	display string
+}
+
+// Hasher turns a (long) line into the key it is counted under
+type Hasher interface {
+	Hash(s string) string
+}
+
+type sha256Hasher struct{}
+
+func (sha256Hasher) Hash(s string) string {
+	return hashString(s)
+}
+
+// fnvHasher keys lines by 128 bit FNV-1a: several times faster than sha256 and reproducible, but
+// not collision resistant. Crafted input can collide at will, so only use it on trusted input,
+// for speed or in tests. Its 32 hex digits keep hashed keys from clashing with raw ones, which
+// are shorter than maxRawKeyLen.
+type fnvHasher struct{}
+
+func (fnvHasher) Hash(s string) string {
+	h := fnv.New128a()
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type dupOptions struct {
+	threshold       int
+	hasher          Hasher
+	checkCollisions bool
+	maxKeyBytes     int  // 0 means no cap
+	groupByFile     bool // report per file, in the order the files were given
+	perFile         bool // count each file on its own rather than pooling them
+	// Lengths are in runes (utf8.RuneCountInString), never bytes, so multibyte text is measured
+	// the way it reads. A rune is a code point, not a grapheme: "e" + combining acute is 2 runes.
+	maxDisplayWidth int // 0 prints the key as is
+	minLineLength   int // shorter lines are skipped
+	// Applied to every line before anything else, to strip or mask what shouldn't tell lines apart
+	// (timestamps, ids). Chain transforms by composing them. Lines are reported as read.
+	preprocess func(string) string
+	// Matches of ignoreRegex are cut out of every line after preprocess, e.g. UUIDs or timestamps
+	// anywhere in it, so lines differing only there count as one. Lines are reported as read.
+	ignoreRegex *regexp.Regexp
+	// Replace every run of ASCII digits with "#" before keying, so "processed 100 records" and
+	// "processed 250 records" count as one line, reported as "processed # records". Lighter than
+	// fuzzy matching, and only ever merges lines whose sole difference is numbers.
+	normalizeNumbers bool
+	// NFC normalize lines before keying, so precomposed and combining forms of the same text match.
+	// The normalized form is what gets reported, unless reportRaw is set.
+	normalizeUnicode bool
+	reportRaw        bool // report lines as read rather than as compared
+	// Report every line (and casing) as redact returns it, see ex3_redact.go
+	redact          func(string) string
+	caseInsensitive bool // compare lines lower-cased (strings.ToLower)
+	// With caseInsensitive, also count how often each casing of a line occurs ("Error": 1,
+	// "ERROR": 2), to spot inconsistent logging
+	casingBreakdown bool
+	// Also record where each line starts, in bytes from the start of its file (of the decompressed
+	// stream for .gz, of the member for archives)
+	withOffsets bool
+	// When set, a line is reported if it occurs at least this often within one file, whatever its
+	// total across files; threshold is then ignored
+	minPerFileCount int
+	// When set, a line is only reported if it occurs in at least this many distinct files, to
+	// find what is shared across files rather than repeated within one. It is counted from the
+	// recorded locations, so it finds none with countOnly and may miss files past locationsCap.
+	minFiles int
+	// Keep at most this many locations per line (across files), counting the rest without
+	// recording where they are. Bounds memory when one line repeats millions of times; note that
+	// minPerFileCount only sees the recorded locations.
+	locationsCap int
+	order        LineOrder
+	opener       fileOpener // nil means os.Open
+	openRetry    retryPolicy
+	binaryInput  binaryPolicy // what to do with files that look binary, skipped by default
+	// Lines of this file (license headers, boilerplate) are neither counted nor reported. They go
+	// through the same preprocessing, normalization and case folding as the input lines.
+	excludeFile string
+	exclude     map[string]bool // keys of the excluded lines, loaded by countLines
+	// Only scan the first headLimit lines of every file (or archive member), to sample big inputs
+	// quickly; 0 scans everything
+	headLimit int
+	// Bytes to scan across all files, after which scanning stops and the report is partial; 0
+	// scans everything. byteBudget tracks it for one run, see ex3_bytebudget.go.
+	byteLimit  int64
+	byteBudget *byteBudget
+	// Count only this fraction of the lines, drawn with sampleSeed, and scale the counts up to
+	// estimates; 0 counts every line (ex3_sample.go)
+	sampleRate float64
+	sampleSeed uint64
+	// Closed to stop the readers at their next line, e.g. ctx.Done() of a checkpointed scan; nil
+	// never stops them
+	stop <-chan struct{}
+	// Key lines by their first keyPrefixLen bytes only (cut back to a rune boundary), for long lines
+	// whose start tells them apart. Lines sharing that prefix are counted as one by design, and
+	// reported by the first of them.
+	keyPrefixLen int
+	// At most parallelFiles files are read at once, runtime.NumCPU() when 0; fewer suit I/O bound
+	// scans. Lines are keyed (normalized, hashed) by the readers, unless parallelHash sets up a
+	// separate pool of that many workers for CPU bound ones. The pool hands lines on in whatever
+	// order they finish, so first-seen order is no longer file order; locations are sorted once
+	// counted (sortLocations).
+	parallelFiles int
+	parallelHash  int
+	// Capacity of the channel feeding lines to the counter, 0 for unbuffered (see the notes at
+	// the top)
+	lineBuffer int
+	// Lines read but not counted yet, at most, whatever the channels hold; 0 for no cap beyond
+	// them. See ex3_budget.go.
+	maxInFlightLines int
+	// Print a frequency histogram rather than the lines, with buckets starting at histogramBounds
+	// (defaultHistogramBounds when empty), as a bar chart with histogramBars
+	histogram       bool
+	histogramBounds []int
+	histogramBars   bool
+	// The count in front of each reported line is right-justified to countWidth and followed by
+	// countSep (a tab when empty). uniqCountWidth and a space match `uniq -c`.
+	countWidth int
+	countSep   string
+	// Only count per line, recording no locations at all, and print just the totals (see
+	// dupSummary). The memory per distinct line is then its key and count. Options reading the
+	// locations (minPerFileCount, groupByFile) find none.
+	countOnly bool
+	// Number lines on through all files in argument order, as if they were concatenated, rather
+	// than from 1 in each; locations still say which file a line is in. The files are then read
+	// one after the other, whatever parallelFiles and parallelHash say.
+	continuousLineNumbers bool
+	// Count every window of this many consecutive lines rather than single lines, see
+	// ex3_blocks.go; 0 or 1 counts lines
+	blockLines int
+	// Also print this many lines before and after every occurrence, see ex3_context.go
+	contextLines int
+	// Print line numbers as ranges, "10-13,20,25-27", rather than every one of them
+	compactLocations bool
+	// Charset of the input files, decoded to UTF-8 before scanning (see ex3_charset.go); nil
+	// reads them as UTF-8
+	charset encoding.Encoding
+	// Called from the reader goroutines, concurrently, as files are scanned
+	progress func(progressEvent)
+	// Where Detect writes its one line summary, tallied in tally; nil for none (ex3_summary.go)
+	summary io.Writer
+	tally   *runTally
+	out     io.Writer
+	warn    io.Writer
+}
+
+// LineOrder is the order lines are reported in
+type LineOrder int
+
+const (
+	OrderByCount LineOrder = iota // most frequent first, ties by text
+	OrderByText
+	// As encountered. With several files that is the order lines reached the counter, which
+	// interleaves the files as their readers happen to run; for a single file it is file order.
+	OrderByFirstSeen
+)
+
+// ParseLineOrder returns the order named "count", "text" or "first-seen"
+func ParseLineOrder(name string) (LineOrder, error) {
+	switch name {
+	case "count":
+		return OrderByCount, nil
+	case "text":
+		return OrderByText, nil
+	case "first-seen":
+		return OrderByFirstSeen, nil
+	}
+	return 0, fmt.Errorf("unknown order %q, want count, text or first-seen", name)
+}
+
+// Lines shorter than this are used as their own key
+const maxRawKeyLen = 32
+
+// Width of the count column of `uniq -c`
+const uniqCountWidth = 7
+
+// Lines the channel into the counter holds; past 64 there is little left to gain
+const defaultLineBuffer = 256
+
+func defaultDupOptions(threshold int) dupOptions {
+	return dupOptions{threshold: threshold, hasher: sha256Hasher{}, openRetry: defaultRetryPolicy, lineBuffer: defaultLineBuffer,
+		out: os.Stdout, warn: os.Stderr}
+}
+
+func (opts *dupOptions) stopped() bool {
+	select {
+	case <-opts.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// reported says whether a counted line makes it into the report
+func (opts *dupOptions) reported(lineDatum lineData) bool {
+	if opts.minFiles > 0 && len(lineDatum.locations) < opts.minFiles {
+		return false
+	}
+	if opts.minPerFileCount > 0 {
+		most := 0
+		for _, lineNums := range lineDatum.locations {
+			most = max(most, len(lineNums))
+		}
+		return most >= opts.minPerFileCount
+	}
+	return lineDatum.count > opts.threshold
+}
+
+// formatCount is the count column of a reported line, separator included
+func (opts *dupOptions) formatCount(n int) string {
+	sep := opts.countSep
+	if sep == "" {
+		sep = "\t"
+	}
+	return fmt.Sprintf("%*d%s", opts.countWidth, n, sep)
+}
+
+// formatLineNums renders the line numbers of a location line
+func (opts *dupOptions) formatLineNums(lineNums []int) string {
+	if opts.compactLocations {
+		return compactRanges(lineNums)
+	}
+	return fmt.Sprintf("%+v", lineNums)
+}
+
+// compactRanges collapses line numbers into runs, "10-13,20,25-27" for 10 11 12 13 20 25 26 27.
+// The input needn't be sorted, and isn't changed.
+func compactRanges(nums []int) string {
+	sorted := slices.Sorted(slices.Values(nums))
+	var b strings.Builder
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] <= sorted[j]+1 {
+			j++
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(sorted[i]))
+		if sorted[j] != sorted[i] {
+			b.WriteByte('-')
+			b.WriteString(strconv.Itoa(sorted[j]))
+		}
+		i = j + 1
+	}
+	return b.String()
 }
 
 func hashString(s string) string {
 	// Accept the risk of collisions
 
-    return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
 }
 
-func getKey(s string) string {
-	if len(s) < 32 {
+// getKey is the key a line is counted under: the line itself when shorter than maxRawKeyLen,
+// its hash otherwise. The length tells the two kinds apart, see hashKey.
+func getKey(s string, h Hasher) string {
+	if len(s) < maxRawKeyLen {
 		return s
 	}
-	return hashString(s)
+	return hashKey(s, h)
+}
+
+// hashKey is the hash of s by h, never shorter than maxRawKeyLen so that no raw key can equal
+// it; a line spelling out the digest of another is at least as long, so it is hashed too. A
+// hasher with shorter digests has them widened to a sha256 of the digest.
+func hashKey(s string, h Hasher) string {
+	key := h.Hash(s)
+	if len(key) < maxRawKeyLen {
+		return hashString(key)
+	}
+	return key
 }
 
-func collectLines(fileName string, lines chan<- rawLineData, wg *sync.WaitGroup) {
+func collectLines(fileName string, opts *dupOptions, emit func(scannedLine), wg *sync.WaitGroup) {
 	defer wg.Done()
+	if opts.progress != nil {
+		defer opts.progress(progressEvent{fileName: fileName, fileDone: true})
+	}
 
-	var input *bufio.Scanner
-	if fileName == "stdin" {
-		input = bufio.NewScanner(os.Stdin)
-	} else {
-		file, err := os.Open(fileName)
-		if err != nil {
-			fmt.Printf("Error in opening %s, discarding it\n", fileName)
-			return
-		}
-		defer file.Close()
-		input = bufio.NewScanner(file)
+	r, closer, err := openSourceWith(fileName, opts.openFile)
+	if err != nil {
+		fmt.Printf("Error in opening %s, discarding it\n", fileName)
+		opts.tally.failed()
+		return
+	}
+	defer closer.Close()
+	if isTarPath(fileName) {
+		scanTar(fileName, r, opts, emit)
+		return
+	}
+	scanText(fileName, r, opts, emit)
+}
+
+// scannedLine is a line as read, before it is keyed
+type scannedLine struct {
+	fileName string
+	lineNum  int
+	text     string
+	offset   int64 // only set when withOffsets is
+	hashOnly bool
+}
+
+func (line scannedLine) key(opts *dupOptions) (rawLineData, bool) {
+	rawLineDatum, ok := newRawLine(line.fileName, line.lineNum, line.text, opts, line.hashOnly)
+	rawLineDatum.offset = line.offset
+	return rawLineDatum, ok
+}
+
+// scanLines hands the lines of one file to emit; hashOnly keys every line by its hash and never
+// reports its text, for binary input
+func scanLines(fileName string, input *bufio.Scanner, opts *dupOptions, hashOnly bool, emit func(scannedLine)) {
+	var offset, next int64
+	if opts.withOffsets {
+		// Count what the scanner consumes rather than len(line)+1, which is off for \r\n endings
+		input.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			advance, token, err := bufio.ScanLines(data, atEOF)
+			if token != nil {
+				offset = next
+			}
+			next += int64(advance)
+			return advance, token, err
+		})
+	}
+	if opts.blockLines > 1 {
+		emit = blockEmit(opts.blockLines, emit)
 	}
+	tracker := progressTracker{fileName: fileName, progress: opts.progress}
+	defer tracker.flush()
 	lineNum := 0
-	for input.Scan() {
+	for (opts.headLimit == 0 || lineNum < opts.headLimit) && input.Scan() {
 		inputText := input.Text()
+		if opts.stopped() || !opts.byteBudget.take(len(inputText)+1) {
+			break
+		}
 		lineNum++
-		rawLineDatum := rawLineData{lineText: getKey(inputText), lineNum: lineNum, fileName: fileName}
-		lines<- rawLineDatum
+		tracker.line(len(inputText))
+		emit(scannedLine{fileName: fileName, lineNum: lineNum, text: inputText, offset: offset, hashOnly: hashOnly})
 	}
 }
 
+// newRawLine keys one line as read, false meaning it is skipped
+func newRawLine(fileName string, lineNum int, inputText string, opts *dupOptions, hashOnly bool) (rawLineData, bool) {
+	keyText := inputText
+	if opts.preprocess != nil {
+		keyText = opts.preprocess(keyText)
+	}
+	if opts.ignoreRegex != nil {
+		keyText = opts.ignoreRegex.ReplaceAllString(keyText, "")
+	}
+	if opts.normalizeNumbers {
+		keyText = maskNumbers(keyText)
+	}
+	if opts.normalizeUnicode {
+		keyText = norm.NFC.String(keyText)
+	}
+	casing := keyText
+	if opts.caseInsensitive {
+		keyText = strings.ToLower(keyText)
+	}
+	if opts.minLineLength > 0 && utf8.RuneCountInString(keyText) < opts.minLineLength {
+		return rawLineData{}, false
+	}
+	keyed := keyText
+	if opts.keyPrefixLen > 0 {
+		keyed = bytePrefix(keyText, opts.keyPrefixLen)
+	}
+	rawLineDatum := rawLineData{lineText: getKey(keyed, opts.hasher), lineNum: lineNum, fileName: fileName}
+	if opts.exclude[rawLineDatum.lineText] {
+		return rawLineData{}, false
+	}
+	if opts.checkCollisions {
+		// Lines sharing a prefix are meant to merge, only a differing prefix is a collision
+		rawLineDatum.fullText = keyed
+	}
+	rawLineDatum.display = displayText(inputText, keyText, opts)
+	if keyed != keyText && rawLineDatum.display == "" {
+		rawLineDatum.display = keyText
+	}
+	if opts.redact != nil {
+		shown := rawLineDatum.display
+		if shown == "" {
+			shown = keyText
+		}
+		rawLineDatum.display = opts.redact(shown)
+	}
+	if hashOnly {
+		rawLineDatum.lineText = hashKey(keyText, opts.hasher)
+		rawLineDatum.display = binaryDisplay(rawLineDatum.lineText, len(inputText))
+	}
+	if opts.caseInsensitive && opts.casingBreakdown {
+		rawLineDatum.casing = casing
+		if opts.redact != nil {
+			rawLineDatum.casing = opts.redact(casing)
+		}
+	}
+	return rawLineDatum, true
+}
+
+type dupCounter struct {
+	opts       *dupOptions
+	counts     map[string]lineData
+	keyBytes   int // estimated footprint of the distinct keys
+	alwaysHash bool
+	seen       int // distinct lines so far
+}
+
+func newDupCounter(opts *dupOptions) *dupCounter {
+	return &dupCounter{opts: opts, counts: make(map[string]lineData)}
+}
+
+func (c *dupCounter) add(rawLineDatum rawLineData) {
+	key := rawLineDatum.lineText
+	if c.alwaysHash && len(key) < maxRawKeyLen {
+		key = hashKey(key, c.opts.hasher)
+	}
+	lineDatum, ok := c.counts[key]
+	if !ok {
+		if !c.opts.countOnly {
+			lineDatum.locations = make(map[string][]int)
+		}
+		if c.opts.withOffsets && !c.opts.countOnly {
+			lineDatum.offsets = make(map[string][]int64)
+		}
+		if rawLineDatum.casing != "" {
+			lineDatum.casings = make(map[string]int)
+		}
+		if c.opts.checkCollisions {
+			lineDatum.firstText = rawLineDatum.fullText
+			lineDatum.firstFile = rawLineDatum.fileName
+			lineDatum.firstLine = rawLineDatum.lineNum
+		}
+		lineDatum.display = rawLineDatum.display
+		lineDatum.seq = c.seen
+		c.seen++
+		c.keyBytes += len(key)
+	} else if c.opts.checkCollisions && lineDatum.firstText != rawLineDatum.fullText {
+		fmt.Fprintf(c.opts.warn, "Warning: key collision between %s:%d and %s:%d\n",
+			lineDatum.firstFile, lineDatum.firstLine, rawLineDatum.fileName, rawLineDatum.lineNum)
+	}
+	// Until truncation every occurrence is recorded, so count is the number of locations
+	switch {
+	case c.opts.countOnly:
+	case c.opts.locationsCap > 0 && lineDatum.count >= c.opts.locationsCap:
+		lineDatum.truncated = true
+	default:
+		lineDatum.locations[rawLineDatum.fileName] = append(lineDatum.locations[rawLineDatum.fileName], rawLineDatum.lineNum)
+		if c.opts.withOffsets {
+			lineDatum.offsets[rawLineDatum.fileName] = append(lineDatum.offsets[rawLineDatum.fileName], rawLineDatum.offset)
+		}
+	}
+	if rawLineDatum.casing != "" {
+		lineDatum.casings[rawLineDatum.casing]++
+	}
+	lineDatum.count++
+	c.counts[key] = lineDatum
+
+	if c.opts.maxKeyBytes > 0 && !c.alwaysHash && c.keyBytes > c.opts.maxKeyBytes {
+		c.switchToHashing()
+	}
+}
+
+func (c *dupCounter) switchToHashing() {
+	// One-time cost: every raw key is hashed into a fresh map
+	c.alwaysHash = true
+	c.keyBytes = 0
+	hashed := make(map[string]lineData, len(c.counts))
+	for key, lineDatum := range c.counts {
+		if len(key) < maxRawKeyLen {
+			key = hashKey(key, c.opts.hasher)
+		}
+		hashed[key] = lineDatum
+		c.keyBytes += len(key)
+	}
+	c.counts = hashed
+	fmt.Fprintf(c.opts.warn, "Warning: key footprint over %d bytes, hashing all lines from now on\n", c.opts.maxKeyBytes)
+}
+
+// countLines counts the lines of files, the counts of a sample scaled up to estimates
+func countLines(opts dupOptions, files ...string) map[string]lineData {
+	counts := countUnscaled(opts, files...)
+	if opts.sampling() {
+		scaleCounts(counts, opts.sampleRate)
+	}
+	return counts
+}
+
+// countUnscaled is countLines leaving the counts of a sample as sampled, for callers adding up
+// several before scaling them once
+func countUnscaled(opts dupOptions, files ...string) map[string]lineData {
+	if opts.excludeFile != "" && opts.exclude == nil {
+		exclude, err := loadExcludeSet(opts.excludeFile, &opts)
+		if err != nil {
+			fmt.Fprintf(opts.warn, "Warning: can't read exclude file %s, nothing excluded: %s\n", opts.excludeFile, err)
+		}
+		opts.exclude = exclude
+	}
+
+	var wg sync.WaitGroup
+	lines := make(chan rawLineData, opts.lineBuffer)
+	budget := newLineBudget(opts.maxInFlightLines)
+	counter := newDupCounter(&opts)
+	done := make(chan bool)
+
+	go func() {
+		for rawLineDatum := range lines {
+			counter.add(rawLineDatum)
+			budget.release()
+		}
+		done <- true
+	}()
+
+	// Readers key their own lines, unless there is a separate pool of hash workers for that
+	emit := func(line scannedLine) {
+		if rawLineDatum, ok := line.key(&opts); ok {
+			budget.acquire()
+			lines <- rawLineDatum
+		}
+	}
+	if opts.continuousLineNumbers {
+		// One reader at a time in argument order, keying inline, so lines reach emit in the order
+		// cat would print them
+		opts.parallelFiles, opts.parallelHash = 1, 0
+	}
+	var hashers sync.WaitGroup
+	var scanned chan scannedLine
+	if opts.parallelHash > 0 {
+		scanned = make(chan scannedLine)
+		emit = func(line scannedLine) {
+			budget.acquire()
+			scanned <- line
+		}
+		for i := 0; i < opts.parallelHash; i++ {
+			hashers.Add(1)
+			go func() {
+				defer hashers.Done()
+				for line := range scanned {
+					if rawLineDatum, ok := line.key(&opts); ok {
+						lines <- rawLineDatum
+					} else {
+						budget.release()
+					}
+				}
+			}()
+		}
+	}
+
+	if opts.sampling() {
+		// Before keying, so lines left out cost nothing
+		emit = sampleEmit(&opts, emit)
+	}
+	if opts.continuousLineNumbers {
+		// Outermost, so lines left out of the sample still take their number
+		sampleLine, total := emit, 0
+		emit = func(line scannedLine) {
+			total++
+			line.lineNum = total
+			sampleLine(line)
+		}
+	}
+
+	readers := make(chan struct{}, workerCount(opts.parallelFiles))
+	for _, f := range files {
+		readers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-readers }()
+			collectLines(f, &opts, emit, &wg)
+		}()
+	}
+	wg.Wait()
+	if scanned != nil {
+		close(scanned)
+		hashers.Wait()
+	}
+	close(lines)
+	<-done
+	sortLocations(counter.counts)
+	return counter.counts
+}
+
+// sortLocations puts the line numbers of every file in ascending order, with their offsets.
+// Lines of a file reach the counter in order from a single reader, but not through a pool of
+// hash workers, which hand them on as they finish.
+func sortLocations(counts map[string]lineData) {
+	for _, lineDatum := range counts {
+		for fileName, lineNums := range lineDatum.locations {
+			if slices.IsSorted(lineNums) {
+				continue
+			}
+			offsets := lineDatum.offsets[fileName]
+			if len(offsets) != len(lineNums) {
+				slices.Sort(lineNums)
+				continue
+			}
+			order := make([]int, len(lineNums))
+			for i := range order {
+				order[i] = i
+			}
+			slices.SortFunc(order, func(a, b int) int { return cmp.Compare(lineNums[a], lineNums[b]) })
+			sortedNums, sortedOffsets := make([]int, len(order)), make([]int64, len(order))
+			for i, j := range order {
+				sortedNums[i], sortedOffsets[i] = lineNums[j], offsets[j]
+			}
+			copy(lineNums, sortedNums)
+			copy(offsets, sortedOffsets)
+		}
+	}
+}
+
+// workerCount is n, or the number of CPUs when n isn't set
+func workerCount(n int) int {
+	if n > 0 {
+		return n
+	}
+	return runtime.NumCPU()
+}
+
+// DupDetectFiles is Detect with a threshold and, optionally, sorted input; see Detect for the rest
 func DupDetectFiles(threshold int, sorted bool, files ...string) {
 	if len(files) == 0 {
 		// Read stdin as no file is specified
@@ -90,94 +709,385 @@ func DupDetectFiles(threshold int, sorted bool, files ...string) {
 		return
 	}
 
+	options := []DetectOption{WithThreshold(threshold)}
 	if sorted {
 		// Assumption; only one file, it is sorted, enough to give starting and ending line nums
-		DupDetectSorted(threshold, files[0])
+		options = append(options, WithSorted(true))
+	}
+	Detect(files, options...)
+}
+
+// DupDetectSummary prints only the totals of DupDetectFiles, without the memory for locations
+func DupDetectSummary(threshold int, files ...string) {
+	Detect(files, WithThreshold(threshold), WithCountOnly(true))
+}
+
+func detectFiles(opts dupOptions, files ...string) {
+	if opts.perFile {
+		// Each file is its own namespace: a line repeated only across files isn't a duplicate
+		for _, f := range files {
+			counts := countLines(opts, f)
+			var lines []string
+			for line, lineDatum := range counts {
+				if opts.reported(lineDatum) {
+					lines = append(lines, line)
+				}
+			}
+			printFileSection(counts, &opts, f, lines)
+			opts.tally.reported(len(lines))
+		}
+		warnBudgetReached(&opts)
+		noteSampled(&opts)
 		return
 	}
+	counts := countLines(opts, files...)
+	printCounts(counts, &opts, files)
+	opts.tally.reported(summarize(counts, &opts).lines)
+	warnBudgetReached(&opts)
+	noteSampled(&opts)
+}
 
-	var wg sync.WaitGroup 
-	lines := make(chan rawLineData)
-	counts := make(map[string]lineData)
-	done := make(chan bool)
+// displayText is what to report for a line, when that differs from its key. It is left empty
+// otherwise, so the common case doesn't keep a second copy of every line.
+func displayText(inputText, keyText string, opts *dupOptions) string {
+	shown := keyText
+	if opts.reportRaw || opts.preprocess != nil || opts.ignoreRegex != nil {
+		shown = inputText
+	}
+	if opts.maxDisplayWidth > 0 {
+		return truncateRunes(shown, opts.maxDisplayWidth)
+	}
+	if shown != keyText || opts.blockLines > 1 {
+		// Blocks are mostly long enough to be keyed by hash, and would be reported as one
+		return shown
+	}
+	return ""
+}
 
-	go func() {
-		for rawLineDatum := range lines {
-			lineDatum, ok := counts[rawLineDatum.lineText]
-			if !ok {
-				lineDatum.locations = make(map[string][]int)
-				lineDatum.locations[rawLineDatum.fileName] = []int{rawLineDatum.lineNum}
+func (lineDatum lineData) displayText(key string) string {
+	if lineDatum.display != "" {
+		return lineDatum.display
+	}
+	return key
+}
+
+// maskNumbers replaces every run of ASCII digits in s with a "#"
+func maskNumbers(s string) string {
+	if strings.IndexFunc(s, isASCIIDigit) < 0 {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	inRun := false
+	for i := 0; i < len(s); i++ {
+		if !isASCIIDigit(rune(s[i])) {
+			b.WriteByte(s[i])
+			inRun = false
+		} else if !inRun {
+			b.WriteByte('#')
+			inRun = true
+		}
+	}
+	return b.String()
+}
+
+func isASCIIDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+// bytePrefix is the first n bytes of s, less any partial rune at the end
+func bytePrefix(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// truncateRunes cuts s down to at most n runes, the last one being an ellipsis when anything was
+// dropped. Counting runes rather than bytes keeps multibyte characters whole. Like every length
+// in dupOptions this is per code point, so a combining mark or an emoji modifier can be split
+// from the rune it decorates.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := 0
+	for i := range s {
+		if runes == n-1 {
+			return s[:i] + "…"
+		}
+		runes++
+	}
+	return s
+}
+
+func printCounts(counts map[string]lineData, opts *dupOptions, files []string) {
+	if opts.histogram {
+		bounds := opts.histogramBounds
+		if len(bounds) == 0 {
+			bounds = defaultHistogramBounds
+		}
+		printHistogram(opts.out, countHistogram(counts, bounds), opts.histogramBars)
+		return
+	}
+	if opts.countOnly {
+		summary := summarize(counts, opts)
+		fmt.Fprintf(opts.out, "%d duplicated lines, %d redundant occurrences\n", summary.lines, summary.redundant)
+		return
+	}
+	if opts.groupByFile {
+		printCountsByFile(counts, opts, files)
+		return
+	}
+	fmt.Fprintln(opts.out, "----")
+	var lines []string
+	for line, lineDatum := range counts {
+		if opts.reported(lineDatum) {
+			lines = append(lines, line)
+		}
+	}
+	sortLines(lines, counts, opts.order)
+	var context lineContext
+	if opts.contextLines > 0 {
+		context = loadContext(counts, lines, opts)
+	}
+	for _, line := range lines {
+		lineDatum := counts[line]
+		fmt.Fprintf(opts.out, "%s%s\n", opts.formatCount(lineDatum.count), lineDatum.displayText(line))
+		// Files by name, not in map order, so the same input always prints the same
+		for _, fileName := range slices.Sorted(maps.Keys(lineDatum.locations)) {
+			lineNums := lineDatum.locations[fileName]
+			if opts.withOffsets {
+				fmt.Fprintf(opts.out, "\tFileName: %s, lineNums: %s, offsets: %+v\n", fileName, opts.formatLineNums(lineNums), lineDatum.offsets[fileName])
 			} else {
-				lineDatum.locations[rawLineDatum.fileName] = append(lineDatum.locations[rawLineDatum.fileName], rawLineDatum.lineNum)
+				fmt.Fprintf(opts.out, "\tFileName: %s, lineNums: %s\n", fileName, opts.formatLineNums(lineNums))
 			}
-			lineDatum.count++
-			counts[rawLineDatum.lineText] = lineDatum
-		}
-		fmt.Println("----")
-		for line, lineDatum := range counts {
-			if lineDatum.count > threshold {
-				fmt.Printf("%d\t%s\n", lineDatum.count, line)
-				for fileName, lineNums := range lineDatum.locations {
-					fmt.Printf("\tFileName: %s, lineNums: %+v\n", fileName, lineNums)
-				}
+			context.print(opts.out, fileName, lineNums, opts.contextLines)
+		}
+		if lineDatum.truncated {
+			fmt.Fprintf(opts.out, "\t(locations truncated after %d)\n", opts.locationsCap)
+		}
+		if len(lineDatum.casings) > 0 {
+			fmt.Fprintf(opts.out, "\tCasings: %s\n", formatCasings(lineDatum.casings))
+		}
+	}
+}
+
+// dupSummary totals the reported lines: how many distinct lines are duplicated, and how many
+// occurrences of them are redundant, i.e. beyond the first of each
+type dupSummary struct {
+	lines     int
+	redundant int
+}
+
+func summarize(counts map[string]lineData, opts *dupOptions) dupSummary {
+	var summary dupSummary
+	for _, lineDatum := range counts {
+		if opts.reported(lineDatum) {
+			summary.lines++
+			summary.redundant += lineDatum.count - 1
+		}
+	}
+	return summary
+}
+
+func printCountsByFile(counts map[string]lineData, opts *dupOptions, files []string) {
+	// Duplicates (by global count) bucketed by the file they occur in
+	byFile := make(map[string][]string)
+	for line, lineDatum := range counts {
+		if opts.reported(lineDatum) {
+			for fileName := range lineDatum.locations {
+				byFile[fileName] = append(byFile[fileName], line)
 			}
 		}
-		done<-true
-	}()
+	}
 
+	// Files in argument order first, then anything else (e.g. archive members) by name
+	order := make([]string, 0, len(byFile))
+	seen := make(map[string]bool)
 	for _, f := range files {
-		wg.Add(1)
-		go collectLines(f, lines, &wg)
+		if _, ok := byFile[f]; ok && !seen[f] {
+			order = append(order, f)
+			seen[f] = true
+		}
+	}
+	var rest []string
+	for f := range byFile {
+		if !seen[f] {
+			rest = append(rest, f)
+		}
+	}
+	sort.Strings(rest)
+	order = append(order, rest...)
+
+	for _, fileName := range order {
+		printFileSection(counts, opts, fileName, byFile[fileName])
+	}
+}
+
+// formatCasings lists the casings most frequent first, as "ERROR":2, "Error":1
+func formatCasings(casings map[string]int) string {
+	names := make([]string, 0, len(casings))
+	for casing := range casings {
+		names = append(names, casing)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if casings[names[i]] != casings[names[j]] {
+			return casings[names[i]] > casings[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, len(names))
+	for i, casing := range names {
+		parts[i] = fmt.Sprintf("%q:%d", casing, casings[casing])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sortLines puts the keys of the given lines in report order
+func sortLines(lines []string, counts map[string]lineData, order LineOrder) {
+	sort.Slice(lines, func(i, j int) bool {
+		a, b := counts[lines[i]], counts[lines[j]]
+		switch order {
+		case OrderByFirstSeen:
+			return a.seq < b.seq
+		case OrderByCount:
+			if a.count != b.count {
+				return a.count > b.count
+			}
+		}
+		ta, tb := a.displayText(lines[i]), b.displayText(lines[j])
+		if ta != tb {
+			return ta < tb
+		}
+		return lines[i] < lines[j]
+	})
+}
+
+// printFileSection prints the given lines' occurrences in fileName, in report order
+func printFileSection(counts map[string]lineData, opts *dupOptions, fileName string, lines []string) {
+	fmt.Fprintf(opts.out, "== %s ==\n", fileName)
+	sortLines(lines, counts, opts.order)
+	for _, line := range lines {
+		lineDatum := counts[line]
+		fmt.Fprintf(opts.out, "%s%s\tlineNums: %s\n", opts.formatCount(lineDatum.count), lineDatum.displayText(line), opts.formatLineNums(lineDatum.locations[fileName]))
 	}
-	wg.Wait()
-	close(lines)
-	<-done
 }
 
 func DupDetectSorted(threshold int, fileName string) {
-	// Assumption; sorted file, enough to give starting and ending line nums
+	if err := checkThreshold(threshold); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	dupDetectSorted(defaultDupOptions(threshold), fileName)
+}
 
-	file, err := os.Open(fileName)
+func dupDetectSorted(opts dupOptions, fileName string) {
+	report, err := sortedReport(&opts, fileName)
 	if err != nil {
 		fmt.Printf("Error in opening %s, discarding it\n", fileName)
+		opts.tally.failed()
 		return
 	}
-	defer file.Close()
-	input := bufio.NewScanner(file)
+	opts.tally.reported(len(report.Entries))
+	fmt.Fprintln(opts.out, "")
+	for _, entry := range report.Entries {
+		fmt.Fprintf(opts.out, "%s%s\tstart: %d, end: %d\n", opts.formatCount(entry.Count), entry.Text, entry.Start, entry.End)
+	}
+}
+
+// sortedReport finds the runs of identical lines in a sorted file
+func sortedReport(opts *dupOptions, fileName string) (*DuplicateReport, error) {
+	// Assumption; sorted file, enough to give starting and ending line nums
+	// With caseInsensitive the file must be sorted case-insensitively too (e.g. sort -f), otherwise
+	// "Apple" and "apple" may not be adjacent and the run gets split
+
+	r, closer, err := openSource(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	input := newLineScanner(opts.decode(r))
+	tracker := progressTracker{fileName: fileName, progress: opts.progress}
+	defer tracker.flush()
 
 	counts := make(map[string]lineData)
 	i := 1
-	prevInputText := ""
+	prevKey := ""
+	havePrev := false
 
 	for input.Scan() {
 		inputText := input.Text()
-		lineDatum, ok := counts[inputText]
+		tracker.line(len(inputText))
+		key := inputText
+		if opts.caseInsensitive {
+			key = strings.ToLower(key)
+		}
+		lineDatum, ok := counts[key]
 		if !ok {
 			lineDatum.locations = make(map[string][]int)
 			lineDatum.locations[fileName] = []int{i}
-			if prevInputText != "" {
-				prevLineDatum := counts[prevInputText]
+			if key != inputText {
+				// Report the run by its first line as written
+				lineDatum.display = inputText
+			}
+			if opts.redact != nil {
+				lineDatum.display = opts.redact(inputText)
+			}
+			if havePrev {
+				prevLineDatum := counts[prevKey]
 				prevLineDatum.locations[fileName] = append(prevLineDatum.locations[fileName], i-1)
-				counts[prevInputText] = prevLineDatum
+				counts[prevKey] = prevLineDatum
 			}
-			prevInputText = inputText
+			prevKey, havePrev = key, true
 		}
 		lineDatum.count++
-		counts[inputText] = lineDatum
+		counts[key] = lineDatum
 		i++
 	}
-	fmt.Println("")
+	if havePrev {
+		// Close the last run
+		lastLineDatum := counts[prevKey]
+		lastLineDatum.locations[fileName] = append(lastLineDatum.locations[fileName], i-1)
+		counts[prevKey] = lastLineDatum
+	}
+	if err := input.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &DuplicateReport{Threshold: opts.threshold}
 	for line, lineDatum := range counts {
-		if lineDatum.count > threshold {
-			fmt.Printf("%d\t%s\tstart: %d, end: %d\n", lineDatum.count, line, lineDatum.locations[fileName][0], lineDatum.locations[fileName][1])
+		if lineDatum.count > opts.threshold {
+			run := lineDatum.locations[fileName]
+			report.Entries = append(report.Entries, DuplicateEntry{
+				Text:  lineDatum.displayText(line),
+				Count: lineDatum.count,
+				File:  fileName,
+				Start: run[0],
+				End:   run[1],
+			})
 		}
 	}
+	report.sort()
+	return report, nil
 }
 
 func DupDetect(threshold int) {
 	// Reads only stdin
+	if err := checkThreshold(threshold); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
 	counts := make(map[string]lineData)
-	input := bufio.NewScanner(os.Stdin)
+	input, closer, err := openLineSource("stdin")
+	if err != nil {
+		fmt.Printf("Error in opening stdin: %s\n", err)
+		return
+	}
+	defer closer.Close()
 	i := 1
 	for input.Scan() {
 		inputText := input.Text()
@@ -0,0 +1,44 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Compressed and archived inputs
+
+	Build logs are often shipped as .gz or as tarballs. A .gz file is decompressed on the fly,
+	a .tar/.tar.gz/.tgz file has every regular-file entry scanned, with the entry name used as
+	the file name in the reported locations. Directories, symlinks etc. inside the archive are skipped.
+**/
+
+package exercises
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func isGzipPath(fileName string) bool {
+	return strings.HasSuffix(fileName, ".gz") || strings.HasSuffix(fileName, ".tgz")
+}
+
+func isTarPath(fileName string) bool {
+	return strings.HasSuffix(fileName, ".tar") || strings.HasSuffix(fileName, ".tar.gz") || strings.HasSuffix(fileName, ".tgz")
+}
+
+func scanTar(archiveName string, r io.Reader, opts *dupOptions, emit func(scannedLine)) {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			fmt.Printf("Error in reading %s, discarding rest of it\n", archiveName)
+			return
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		scanText(hdr.Name, tr, opts, emit)
+	}
+}
@@ -0,0 +1,98 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Compressed and archived inputs
+**/
+
+package exercises
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func buildTar(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	entries := []struct {
+		hdr  tar.Header
+		body string
+	}{
+		{tar.Header{Name: "logs/", Typeflag: tar.TypeDir, Mode: 0o755}, ""},
+		{tar.Header{Name: "logs/a.log", Typeflag: tar.TypeReg, Mode: 0o644}, "boot\nerror: disk full\nok\n"},
+		{tar.Header{Name: "logs/b.log", Typeflag: tar.TypeReg, Mode: 0o644}, "start\nerror: disk full\n"},
+		{tar.Header{Name: "logs/c.log", Typeflag: tar.TypeSymlink, Linkname: "a.log"}, ""},
+	}
+	for _, e := range entries {
+		e.hdr.Size = int64(len(e.body))
+		if err := tw.WriteHeader(&e.hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestTarInputs(t *testing.T) {
+	tarData := buildTar(t)
+	for name, data := range map[string][]byte{
+		"logs.tar":    tarData,
+		"logs.tar.gz": gzipBytes(t, tarData),
+		"logs.tgz":    gzipBytes(t, tarData),
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			counts := countLines(defaultDupOptions(1), path)
+
+			lineDatum := counts["error: disk full"]
+			if lineDatum.count != 2 {
+				t.Fatalf("count = %d, want 2", lineDatum.count)
+			}
+			want := map[string][]int{"logs/a.log": {2}, "logs/b.log": {2}}
+			if !reflect.DeepEqual(lineDatum.locations, want) {
+				t.Errorf("locations = %v, want %v", lineDatum.locations, want)
+			}
+			// The symlink to a.log must not be followed
+			if got := counts["boot"].count; got != 1 {
+				t.Errorf("boot count = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestGzipInput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.log.gz")
+	if err := os.WriteFile(path, gzipBytes(t, []byte("x\ny\nx\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	counts := countLines(defaultDupOptions(1), path)
+	if !reflect.DeepEqual(counts["x"].locations, map[string][]int{path: {1, 3}}) {
+		t.Errorf("locations = %v", counts["x"].locations)
+	}
+}
@@ -0,0 +1,62 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Regressions against a baseline
+
+	For CI: keep the JSON report of a known good run as the baseline, and report only what got
+	worse since. A line is a regression when it is newly duplicated or its count went up; lines
+	that went down or away are improvements and not reported. Lines are matched by their reported
+	text, so the baseline should come from a run with the same options.
+**/
+
+package exercises
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ReadReport loads a report written as JSON, e.g. by WriteReport
+func ReadReport(path string) (*DuplicateReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r DuplicateReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// Since returns the entries of r that are new or more frequent than in baseline, with
+// BaselineCount set to the count in baseline
+func (r *DuplicateReport) Since(baseline *DuplicateReport) *DuplicateReport {
+	before := make(map[string]int, len(baseline.Entries))
+	for _, entry := range baseline.Entries {
+		before[entry.Text] += entry.Count
+	}
+
+	delta := &DuplicateReport{Threshold: r.Threshold}
+	for _, entry := range r.Entries {
+		if entry.Count > before[entry.Text] {
+			entry.BaselineCount = before[entry.Text]
+			delta.Entries = append(delta.Entries, entry)
+		}
+	}
+	return delta
+}
+
+// DetectSince is DetectReport over unsorted files, keeping only the regressions against the
+// report stored at baselinePath
+func DetectSince(threshold int, baselinePath string, files ...string) (*DuplicateReport, error) {
+	baseline, err := ReadReport(baselinePath)
+	if err != nil {
+		return nil, err
+	}
+	report, err := DetectReport(threshold, false, files...)
+	if err != nil {
+		return nil, err
+	}
+	return report.Since(baseline), nil
+}
@@ -0,0 +1,45 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Regressions against a baseline
+**/
+
+package exercises
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDetectSince(t *testing.T) {
+	dir := t.TempDir()
+	before := writeTestFile(t, dir, "before", "same\nsame\ngrows\ngrows\nshrinks\nshrinks\nshrinks\n")
+	baseline := filepath.Join(dir, "baseline.json")
+	report, err := DetectReport(1, false, before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteReport(baseline, report, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	after := writeTestFile(t, dir, "after", "same\nsame\ngrows\ngrows\ngrows\nshrinks\nshrinks\nnew\nnew\n")
+	delta, err := DetectSince(1, baseline, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &DuplicateReport{Threshold: 1, Entries: []DuplicateEntry{
+		{Text: "grows", Count: 3, Locations: map[string][]int{after: {3, 4, 5}}, BaselineCount: 2},
+		{Text: "new", Count: 2, Locations: map[string][]int{after: {8, 9}}},
+	}}
+	if !reflect.DeepEqual(delta, want) {
+		t.Errorf("delta = %+v, want %+v", delta, want)
+	}
+
+	if _, err := DetectSince(1, filepath.Join(dir, "missing.json"), after); err == nil {
+		t.Error("expected an error for a missing baseline")
+	}
+	if _, err := DetectSince(1, before, after); err == nil {
+		t.Error("expected an error for a baseline that isn't JSON")
+	}
+}
@@ -0,0 +1,44 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Binary input
+
+	Scanning a binary file for lines gives arbitrary byte soup, which would then be printed to the
+	terminal. A file (or archive member) with a NUL byte in its first few KB is taken to be binary,
+	the same test grep and git use. By default it is skipped with a warning; hashBinary scans it
+	anyway, keying every line by its hash and reporting lines by hash and length only.
+**/
+
+package exercises
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+type binaryPolicy int
+
+const (
+	skipBinary binaryPolicy = iota
+	hashBinary
+)
+
+const binarySniffLen = 8 << 10
+
+// scanText scans r unless it looks binary, see binaryPolicy
+func scanText(fileName string, r io.Reader, opts *dupOptions, emit func(scannedLine)) {
+	br := bufio.NewReaderSize(opts.decode(r), binarySniffLen)
+	// A short file gives io.EOF with everything it has, that's still a valid sample
+	head, _ := br.Peek(binarySniffLen)
+	binary := bytes.IndexByte(head, 0) >= 0
+	if binary && opts.binaryInput == skipBinary {
+		fmt.Fprintf(opts.warn, "Warning: %s looks binary, skipping it\n", fileName)
+		return
+	}
+	scanLines(fileName, newLineScanner(br), opts, binary, emit)
+}
+
+func binaryDisplay(key string, length int) string {
+	return fmt.Sprintf("<binary line %.12s, %d bytes>", key, length)
+}
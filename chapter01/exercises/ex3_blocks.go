@@ -0,0 +1,44 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Duplicate blocks
+
+	Copy-pasted config stanzas repeat as blocks, and their lines one by one are mostly too
+	common ("}", "enabled: true") to say anything. With blockLines set to W, every window of W
+	consecutive lines of a file is counted as one unit instead of the single lines, keyed by the
+	lines joined with "\n", and reported at the line number of its first line. Everything else
+	applies to the joined text as to a line: preprocessing, case folding, hashing of long keys.
+	The text of every distinct block is kept for the report though, as blocks are mostly long
+	enough to be hashed and a hash says nothing about the block.
+
+	Windows are fixed and overlap: a repeated block of W+2 lines is reported as the 3 windows it
+	contains, rather than merged into one maximal block. That keeps one pass and one key per
+	window; the consecutive start lines in the report show where a longer block runs on. A
+	file shorter than W has no windows. The exclude file and continuous line numbers work on
+	single lines and don't combine with blocks.
+**/
+
+package exercises
+
+import "strings"
+
+// blockEmit turns the lines of one file, as emitted by scanLines, into its windows of size lines
+func blockEmit(size int, emit func(scannedLine)) func(scannedLine) {
+	window := make([]scannedLine, 0, size)
+	texts := make([]string, size)
+	return func(line scannedLine) {
+		if len(window) == size {
+			copy(window, window[1:])
+			window = window[:size-1]
+		}
+		window = append(window, line)
+		if len(window) < size {
+			return
+		}
+		for i, l := range window {
+			texts[i] = l.text
+		}
+		block := window[0]
+		block.text = strings.Join(texts, "\n")
+		emit(block)
+	}
+}
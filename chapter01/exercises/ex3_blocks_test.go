@@ -0,0 +1,51 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Duplicate blocks
+**/
+
+package exercises
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBlockLines(t *testing.T) {
+	dir := t.TempDir()
+	stanza := "[server]\nport = 80\nenabled = true\n"
+	a := writeTestFile(t, dir, "a.ini", "# a\n"+stanza+"[client]\nenabled = true\n")
+	b := writeTestFile(t, dir, "b.ini", stanza+"# b\n")
+
+	report, err := DetectReportWith([]string{a, b}, WithBlockLines(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DuplicateEntry{
+		{Text: "[server]\nport = 80\nenabled = true", Count: 2, Locations: map[string][]int{a: {2}, b: {1}}},
+	}
+	if !reflect.DeepEqual(report.Entries, want) {
+		t.Errorf("blocks of 3: %+v, want %+v", report.Entries, want)
+	}
+
+	// A longer repeated block is reported as every window it contains
+	report, err = DetectReportWith([]string{a, b}, WithBlockLines(2), WithThreshold(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var starts [][]int
+	for _, entry := range report.Entries {
+		starts = append(starts, entry.Locations[a])
+	}
+	if len(report.Entries) != 2 || !reflect.DeepEqual(starts, [][]int{{2}, {3}}) {
+		t.Errorf("blocks of 2: %+v", report.Entries)
+	}
+
+	// Single lines again: "enabled = true" repeats within a
+	report, err = DetectReportWith([]string{a}, WithBlockLines(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Entries) != 1 || report.Entries[0].Text != "enabled = true" {
+		t.Errorf("blocks of 1: %+v", report.Entries)
+	}
+}
@@ -0,0 +1,55 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	In-flight line budget
+
+	Lines wait between being read and being counted: in the channel to the counter, and with hash
+	workers in the channel to them and in the workers themselves. How many is up to the channel
+	capacities, so raising lineBuffer for throughput also raises what fast readers can pile up
+	in front of a slow counter. maxInFlightLines caps that total whatever the channels say: a
+	reader takes a slot from the budget before passing a line on and the counter gives it back
+	once the line is counted, so readers block, rather than memory grow, when the counter falls
+	behind. Lines dropped before counting (too short, excluded) give their slot back too.
+
+	The budget is in lines, not bytes; a line can be up to maxLineBytes.
+**/
+
+package exercises
+
+import "sync/atomic"
+
+// lineBudget is a counting semaphore of lines in flight; a nil budget never blocks
+type lineBudget struct {
+	slots    chan struct{}
+	inFlight atomic.Int64
+	peak     atomic.Int64 // highest inFlight seen, for tests
+}
+
+func newLineBudget(n int) *lineBudget {
+	if n <= 0 {
+		return nil
+	}
+	return &lineBudget{slots: make(chan struct{}, n)}
+}
+
+// acquire takes a slot, waiting for one to be released if there is none
+func (b *lineBudget) acquire() {
+	if b == nil {
+		return
+	}
+	b.slots <- struct{}{}
+	n := b.inFlight.Add(1)
+	for {
+		peak := b.peak.Load()
+		if n <= peak || b.peak.CompareAndSwap(peak, n) {
+			return
+		}
+	}
+}
+
+func (b *lineBudget) release() {
+	if b == nil {
+		return
+	}
+	b.inFlight.Add(-1)
+	<-b.slots
+}
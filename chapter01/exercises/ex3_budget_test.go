@@ -0,0 +1,89 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	In-flight line budget
+**/
+
+package exercises
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A slow consumer behind a channel with room for every line: without the budget the producers
+// would buffer all of them
+func TestLineBudgetSlowConsumer(t *testing.T) {
+	const producers, perProducer, limit = 4, 200, 16
+	budget := newLineBudget(limit)
+	lines := make(chan int, producers*perProducer)
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				budget.acquire()
+				lines <- i
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	consumed := 0
+	for range lines {
+		if consumed%50 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		if n := budget.inFlight.Load(); n > limit {
+			t.Fatalf("%d lines in flight, budget %d", n, limit)
+		}
+		consumed++
+		budget.release()
+	}
+	if consumed != producers*perProducer {
+		t.Errorf("consumed %d lines, want %d", consumed, producers*perProducer)
+	}
+	if peak := budget.peak.Load(); peak > limit || peak == 0 {
+		t.Errorf("peak %d lines in flight, want 1 to %d", peak, limit)
+	}
+}
+
+func TestMaxInFlightLines(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := 0; i < 3; i++ {
+		content := strings.Repeat(fmt.Sprintf("shared\nonly in %d\n", i), 100) + "short\n"
+		files = append(files, writeTestFile(t, dir, fmt.Sprintf("%d.txt", i), content))
+	}
+	for _, parallelHash := range []int{0, 2} {
+		report, err := DetectReportWith(files, WithLineBuffer(1000), WithMaxInFlightLines(2),
+			WithMinLineLength(6), WithParallelism(Parallelism{Files: 3, Hash: parallelHash}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts := make(map[string]int)
+		for _, entry := range report.Entries {
+			counts[entry.Text] = entry.Count
+		}
+		want := map[string]int{"shared": 300, "only in 0": 100, "only in 1": 100, "only in 2": 100}
+		if fmt.Sprint(counts) != fmt.Sprint(want) {
+			t.Errorf("hash workers %d: counts %v, want %v", parallelHash, counts, want)
+		}
+	}
+}
+
+func TestLineBudgetNil(t *testing.T) {
+	budget := newLineBudget(0)
+	if budget != nil {
+		t.Fatal("budget of 0 should be no budget")
+	}
+	budget.acquire()
+	budget.release()
+}
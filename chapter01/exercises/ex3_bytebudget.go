@@ -0,0 +1,59 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Byte budget
+
+	headLimit samples every file; a byte budget bounds the cost of the whole scan instead, for
+	sampling a corpus too big to read. The readers draw every line they scan (its bytes and the
+	newline) from one budget shared across them, atomically as they run concurrently. The first
+	line that doesn't fit stops its reader, and every other reader at its next line: what was
+	scanned by then is counted and reported as usual, with BudgetReached set so the report says
+	it is partial. Which files got how far depends on how the readers were scheduled.
+**/
+
+package exercises
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// byteBudget is shared by the readers of one scan; a nil budget is unlimited
+type byteBudget struct {
+	limit   int64
+	used    atomic.Int64
+	reached atomic.Bool
+}
+
+func newByteBudget(limit int64) *byteBudget {
+	if limit <= 0 {
+		return nil
+	}
+	return &byteBudget{limit: limit}
+}
+
+// take draws n bytes, false once the budget can't cover them; those bytes are then not scanned
+func (b *byteBudget) take(n int) bool {
+	if b == nil {
+		return true
+	}
+	if b.reached.Load() {
+		return false
+	}
+	if b.used.Add(int64(n)) > b.limit {
+		b.used.Add(int64(-n))
+		b.reached.Store(true)
+		return false
+	}
+	return true
+}
+
+func warnBudgetReached(opts *dupOptions) {
+	if opts.byteBudget.exhausted() {
+		fmt.Fprintf(opts.warn, "Warning: byte budget of %d reached, the counts are of part of the input\n", opts.byteBudget.limit)
+	}
+}
+
+// exhausted says whether scanning stopped short of the input
+func (b *byteBudget) exhausted() bool {
+	return b != nil && b.reached.Load()
+}
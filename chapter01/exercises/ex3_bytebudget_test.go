@@ -0,0 +1,68 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Byte budget
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestByteBudget(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := 0; i < 4; i++ {
+		// 100 lines of 10 bytes with the newline, 1000 bytes per file
+		content := strings.Repeat("same line\n", 100)
+		files = append(files, writeTestFile(t, dir, fmt.Sprintf("%d.txt", i), content))
+	}
+
+	for _, parallel := range []Parallelism{{Files: 1}, {Files: 4}, {Files: 4, Hash: 2}} {
+		report, err := DetectReportWith(files, WithByteBudget(1500), WithParallelism(parallel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.BudgetReached {
+			t.Errorf("%+v: budget not reached", parallel)
+		}
+		if len(report.Entries) != 1 || report.Entries[0].Count != 150 {
+			t.Errorf("%+v: entries %+v, want the line 150 times, 1500 bytes worth", parallel, report.Entries)
+		}
+	}
+
+	report, err := DetectReportWith(files, WithByteBudget(4000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.BudgetReached || report.Entries[0].Count != 400 {
+		t.Errorf("budget covering the input: reached %v, count %d, want false and 400",
+			report.BudgetReached, report.Entries[0].Count)
+	}
+
+	var out, warn bytes.Buffer
+	Detect(files, WithByteBudget(100), WithOutput(&out), WithWarnings(&warn))
+	if !strings.Contains(warn.String(), "byte budget of 100 reached") {
+		t.Errorf("warnings %q, want the budget reached", warn.String())
+	}
+	if !strings.Contains(out.String(), "same line") {
+		t.Errorf("output %q, want the partial counts", out.String())
+	}
+}
+
+func TestByteBudgetReusedOptions(t *testing.T) {
+	f := writeTestFile(t, t.TempDir(), "a", strings.Repeat("same line\n", 100))
+	options := []DetectOption{WithByteBudget(500)}
+	for run := range 2 {
+		report, err := DetectReportWith([]string{f}, options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.BudgetReached || len(report.Entries) != 1 || report.Entries[0].Count != 50 {
+			t.Errorf("run %d: reached %v, entries %+v; want the line 50 times", run, report.BudgetReached, report.Entries)
+		}
+	}
+}
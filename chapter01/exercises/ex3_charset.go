@@ -0,0 +1,45 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Input charsets
+
+	Lines are compared as UTF-8, which legacy logs in Latin-1 or UTF-16 aren't: Latin-1 accents
+	come out as invalid bytes, and UTF-16 has a NUL in every ASCII character, so it looks binary
+	and gets skipped. With a charset set, every input file (and archive member) is decoded to
+	UTF-8 before it is sniffed and scanned; a byte order mark at the start overrides the charset,
+	so UTF-8 and UTF-16 files with one are read right whatever was asked for. Offsets are then in
+	the decoded text. The exclude file is not decoded, it is written for the tool, in UTF-8.
+**/
+
+package exercises
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// ParseCharset looks up a charset by one of its WHATWG names or labels ("latin1", "utf-16le",
+// "windows-1252", "shift_jis", ...). UTF-8 gives nil: lines are read as is, the default.
+func ParseCharset(name string) (encoding.Encoding, error) {
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown charset %q: %w", name, err)
+	}
+	if enc == unicode.UTF8 || strings.EqualFold(name, "utf-8") {
+		return nil, nil
+	}
+	return enc, nil
+}
+
+// decode returns r decoded from opts.charset to UTF-8, r itself when no charset is set
+func (opts *dupOptions) decode(r io.Reader) io.Reader {
+	if opts.charset == nil {
+		return r
+	}
+	return transform.NewReader(r, unicode.BOMOverride(opts.charset.NewDecoder()))
+}
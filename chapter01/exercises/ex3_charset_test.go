@@ -0,0 +1,84 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Input charsets
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestCharset(t *testing.T) {
+	dir := t.TempDir()
+	text := "café\nnaïve\ncafé\nplain\nnaïve\ncafé\n"
+
+	utf16, err := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder().String(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := writeTestFile(t, dir, "utf16le.log", utf16)
+	want := []DuplicateEntry{
+		{Text: "café", Count: 3, Locations: map[string][]int{f: {1, 3, 6}}},
+		{Text: "naïve", Count: 2, Locations: map[string][]int{f: {2, 5}}},
+	}
+
+	// Undecoded, UTF-16 is all NULs and looks binary
+	var warn bytes.Buffer
+	report, err := DetectReportWith([]string{f}, WithWarnings(&warn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Entries) != 0 || !strings.Contains(warn.String(), "looks binary") {
+		t.Errorf("read as UTF-8: %+v, warned %q; want nothing, binary", report.Entries, warn.String())
+	}
+
+	utf16le, err := ParseCharset("utf-16le")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err = DetectReportWith([]string{f}, WithCharset(utf16le))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Entries, want) {
+		t.Errorf("decoded from UTF-16LE: %+v, want %+v", report.Entries, want)
+	}
+
+	// A byte order mark wins over the charset asked for
+	latin1, err := ParseCharset("latin1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bom := writeTestFile(t, dir, "bom.log", "\xff\xfe"+utf16)
+	report, err = DetectReportWith([]string{bom}, WithCharset(latin1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Entries) != 2 || report.Entries[0].Text != "café" {
+		t.Errorf("UTF-16LE with a BOM, read as latin1: %+v", report.Entries)
+	}
+
+	legacy := writeTestFile(t, dir, "latin1.log", "caf\xe9\nok\ncaf\xe9\n")
+	report, err = DetectReportWith([]string{legacy}, WithCharset(latin1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Entries) != 1 || report.Entries[0].Text != "café" {
+		t.Errorf("latin1: %+v", report.Entries)
+	}
+}
+
+func TestParseCharset(t *testing.T) {
+	if enc, err := ParseCharset("UTF-8"); enc != nil || err != nil {
+		t.Errorf("UTF-8: %v, %v; want nil, nil", enc, err)
+	}
+	if _, err := ParseCharset("klingon"); err == nil {
+		t.Error("expected an error for an unknown charset")
+	}
+}
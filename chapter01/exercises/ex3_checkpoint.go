@@ -0,0 +1,268 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Checkpoints
+
+	A scan of a huge corpus can run for hours; interrupted, it used to start again from nothing.
+	DetectCheckpointed scans the files one after the other, each into counts of its own, and only
+	merges a file into the totals once it is read to the end. Every so often, and when the scan is
+	interrupted, the totals and the list of files done go to a checkpoint file. Resuming loads the
+	checkpoint and skips the files done; the file being read at the interruption is dropped and
+	read again in full, which is simpler than recording how far into a file the counts go and can
+	never count a line twice.
+
+	The checkpoint is a gob, so keys that aren't valid UTF-8 survive it: a version, the files done
+	and the counts with their locations. Resume with the same options as the interrupted run; the
+	counts are keyed by them. Continuous line numbers number across files, which per file counts
+	can't do, so they are refused.
+
+	With maxKeyBytes each file's counter may switch to hashing on its own, so the same short line
+	could be a raw key in the totals and a hashed one in a file. The totals hold one key mode for
+	the run: they switch to hashing once the distinct keys of the totals and the next file would
+	go over maxKeyBytes, which a file that switched always does, and from then on the raw keys of
+	every file are hashed before it is merged. The mode is saved with the checkpoint.
+**/
+
+package exercises
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+)
+
+// Checkpoint says where DetectCheckpointed saves its progress and whether it starts from there
+type Checkpoint struct {
+	Path   string
+	Every  time.Duration // least time between two saves, 0 saves after every file
+	Resume bool          // load Path, if it exists, and skip the files it has done
+}
+
+const checkpointVersion = 1
+
+// checkpointFile is what a checkpoint holds, lineData with its fields exported for gob
+type checkpointFile struct {
+	Version int
+	Done    []string
+	Seen    int // distinct lines so far, the seq of the next one
+	Hashed  bool
+	Counts  map[string]checkpointEntry
+}
+
+type checkpointEntry struct {
+	Count     int
+	Seq       int
+	Locations map[string][]int
+	Offsets   map[string][]int64
+	Truncated bool
+	Casings   map[string]int
+	Display   string
+}
+
+// checkpointed is the state of a checkpointed scan: the totals of the files done
+type checkpointed struct {
+	done     []string
+	seen     int
+	counts   map[string]lineData
+	keyBytes int  // footprint of the keys of counts, as dupCounter estimates it
+	hashed   bool // every key is hashed, see above
+}
+
+// DetectCheckpointed is DetectReportWith reading files one after the other and checkpointing
+// as it goes, see above. When ctx is done it saves a last checkpoint and returns ctx.Err().
+func DetectCheckpointed(ctx context.Context, files []string, cp Checkpoint, options ...DetectOption) (*DuplicateReport, error) {
+	d := newDetectOptions(options...)
+	if err := errors.Join(checkThreshold(d.dup.threshold), checkSampleRate(d.dup.sampleRate)); err != nil {
+		return nil, err
+	}
+	if d.sorted || d.dup.continuousLineNumbers {
+		return nil, errors.New("checkpoints need unsorted input without continuous line numbers")
+	}
+	opts := d.dup
+	opts.stop = ctx.Done()
+	if opts.excludeFile != "" {
+		// Once, rather than for every file
+		exclude, err := loadExcludeSet(opts.excludeFile, &opts)
+		if err != nil {
+			return nil, fmt.Errorf("exclude file %s: %w", opts.excludeFile, err)
+		}
+		opts.exclude = exclude
+	}
+
+	state := &checkpointed{counts: make(map[string]lineData)}
+	if cp.Resume {
+		loaded, err := loadCheckpoint(cp.Path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if loaded != nil {
+			state = loaded
+		}
+	}
+
+	lastSave := time.Now()
+	for _, f := range files {
+		if slices.Contains(state.done, f) {
+			continue
+		}
+		// Scaled once all are added up, rounding each file's estimate would skew the total
+		counts := countUnscaled(opts, f)
+		if ctx.Err() != nil {
+			// f may be partly read, it is read again on resume
+			return nil, errors.Join(ctx.Err(), state.save(cp.Path))
+		}
+		state.merge(f, state.keyMode(counts, &opts))
+		if time.Since(lastSave) >= cp.Every {
+			if err := state.save(cp.Path); err != nil {
+				return nil, err
+			}
+			lastSave = time.Now()
+		}
+	}
+	if err := state.save(cp.Path); err != nil {
+		return nil, err
+	}
+	if opts.sampling() {
+		scaleCounts(state.counts, opts.sampleRate)
+	}
+	return newDuplicateReport(state.counts, &opts), nil
+}
+
+// keyMode switches the totals to hashing when counts would take them over maxKeyBytes, and
+// returns counts keyed the way the totals are
+func (c *checkpointed) keyMode(counts map[string]lineData, opts *dupOptions) map[string]lineData {
+	if opts.maxKeyBytes <= 0 {
+		return counts
+	}
+	if !c.hashed {
+		footprint := c.keyBytes
+		for key := range counts {
+			footprint += len(key)
+		}
+		if footprint <= opts.maxKeyBytes {
+			return counts
+		}
+		c.hashed = true
+		c.counts = hashRawKeys(c.counts, opts.hasher)
+		c.keyBytes = 0
+		for key := range c.counts {
+			c.keyBytes += len(key)
+		}
+		fmt.Fprintf(opts.warn, "Warning: key footprint over %d bytes, hashing all lines from now on\n", opts.maxKeyBytes)
+	}
+	return hashRawKeys(counts, opts.hasher)
+}
+
+// hashRawKeys returns counts with every raw key replaced by its hash. Within one map a raw key
+// can't share its hash with another key, see hashKey, so no two entries land on one key.
+func hashRawKeys(counts map[string]lineData, h Hasher) map[string]lineData {
+	hashed := make(map[string]lineData, len(counts))
+	for key, lineDatum := range counts {
+		if len(key) < maxRawKeyLen {
+			key = hashKey(key, h)
+		}
+		hashed[key] = lineDatum
+	}
+	return hashed
+}
+
+// merge adds the counts of fileName, read in full, to the totals. A file only has locations in
+// its own counts, so those are taken over as they are.
+func (c *checkpointed) merge(fileName string, counts map[string]lineData) {
+	// The file's lines in the order it first saw them, to carry on the seq of the totals
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int { return counts[a].seq - counts[b].seq })
+
+	for _, key := range keys {
+		fileDatum := counts[key]
+		total, ok := c.counts[key]
+		if !ok {
+			fileDatum.seq = c.seen
+			c.seen++
+			c.keyBytes += len(key)
+			c.counts[key] = fileDatum
+			continue
+		}
+		total.count += fileDatum.count
+		total.truncated = total.truncated || fileDatum.truncated
+		total.locations = mergeMap(total.locations, fileDatum.locations)
+		total.offsets = mergeMap(total.offsets, fileDatum.offsets)
+		for casing, n := range fileDatum.casings {
+			if total.casings == nil {
+				total.casings = make(map[string]int)
+			}
+			total.casings[casing] += n
+		}
+		c.counts[key] = total
+	}
+	c.done = append(c.done, fileName)
+}
+
+func mergeMap[V any](into, from map[string]V) map[string]V {
+	if len(from) == 0 {
+		return into
+	}
+	if into == nil {
+		into = make(map[string]V, len(from))
+	}
+	for k, v := range from {
+		into[k] = v
+	}
+	return into
+}
+
+func (c *checkpointed) save(path string) error {
+	file := checkpointFile{Version: checkpointVersion, Done: c.done, Seen: c.seen, Hashed: c.hashed,
+		Counts: make(map[string]checkpointEntry, len(c.counts))}
+	for key, lineDatum := range c.counts {
+		file.Counts[key] = checkpointEntry{
+			Count:     lineDatum.count,
+			Seq:       lineDatum.seq,
+			Locations: lineDatum.locations,
+			Offsets:   lineDatum.offsets,
+			Truncated: lineDatum.truncated,
+			Casings:   lineDatum.casings,
+			Display:   lineDatum.display,
+		}
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(file)
+	})
+}
+
+func loadCheckpoint(path string) (*checkpointed, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var file checkpointFile
+	if err := gob.NewDecoder(f).Decode(&file); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+	if file.Version != checkpointVersion {
+		return nil, fmt.Errorf("checkpoint %s: version %d, want %d", path, file.Version, checkpointVersion)
+	}
+	c := &checkpointed{done: file.Done, seen: file.Seen, hashed: file.Hashed,
+		counts: make(map[string]lineData, len(file.Counts))}
+	for key, entry := range file.Counts {
+		c.keyBytes += len(key)
+		c.counts[key] = lineData{
+			count:     entry.Count,
+			seq:       entry.Seq,
+			locations: entry.Locations,
+			offsets:   entry.Offsets,
+			truncated: entry.Truncated,
+			casings:   entry.Casings,
+			display:   entry.Display,
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,121 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Checkpoints
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// withOpener reads files through open, counting on it being called
+func withOpener(open fileOpener) DetectOption {
+	return func(d *DetectOptions) { d.dup.opener = open }
+}
+
+func TestCheckpointResume(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		writeTestFile(t, dir, "a", "alpha\nbeta\nalpha\nA line long enough to be keyed by its hash, not raw\n"),
+		writeTestFile(t, dir, "b", "beta\ngamma\nA line long enough to be keyed by its hash, not raw\n"),
+		writeTestFile(t, dir, "c", "gamma\nalpha\ndelta\n"),
+	}
+	want, err := DetectReportWith(files, WithCaseInsensitive(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cp := Checkpoint{Path: filepath.Join(dir, "scan.checkpoint"), Resume: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	var opened []string
+	open := func(name string) (io.ReadCloser, error) {
+		opened = append(opened, filepath.Base(name))
+		if name == files[1] {
+			cancel() // interrupted as the second file starts
+		}
+		return os.Open(name)
+	}
+	if _, err := DetectCheckpointed(ctx, files, cp, WithCaseInsensitive(true), withOpener(open)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("interrupted scan: error %v, want context.Canceled", err)
+	}
+	state, err := loadCheckpoint(cp.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(state.done, files[:1]) {
+		t.Errorf("checkpoint has %v done, want only the first file", state.done)
+	}
+
+	opened = nil
+	got, err := DetectCheckpointed(context.Background(), files, cp, WithCaseInsensitive(true), withOpener(open))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(opened, []string{"b", "c"}) {
+		t.Errorf("resumed scan read %v, want the files not done, b and c", opened)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resumed report %+v\nwant, from an uninterrupted run, %+v", got, want)
+	}
+
+	// Without Resume the checkpoint is only written, the scan starts over
+	opened = nil
+	if _, err := DetectCheckpointed(context.Background(), files, Checkpoint{Path: cp.Path}, withOpener(open)); err != nil {
+		t.Fatal(err)
+	}
+	if len(opened) != len(files) {
+		t.Errorf("fresh scan read %v, want every file", opened)
+	}
+}
+
+func TestCheckpointRefusesContinuousLineNumbers(t *testing.T) {
+	cp := Checkpoint{Path: filepath.Join(t.TempDir(), "scan.checkpoint")}
+	if _, err := DetectCheckpointed(context.Background(), nil, cp, WithContinuousLineNumbers(true)); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestCheckpointKeyModePerRun(t *testing.T) {
+	dir := t.TempDir()
+	// a goes over the key cap and hashes its lines, b on its own stays raw
+	var sb strings.Builder
+	for i := range 50 {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	sb.WriteString("dup\n")
+	files := []string{
+		writeTestFile(t, dir, "a", sb.String()),
+		writeTestFile(t, dir, "b", "dup\nline 3\n"),
+	}
+
+	var warn bytes.Buffer
+	cp := Checkpoint{Path: filepath.Join(dir, "checkpoint")}
+	report, err := DetectCheckpointed(context.Background(), files, cp,
+		WithThreshold(1), WithMaxKeyBytes(100), WithWarnings(&warn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Hashed keys have no text to show, dup and line 3 are told apart by where they are
+	var got [][]int
+	for _, entry := range report.Entries {
+		got = append(got, []int{entry.Count, entry.Locations[files[0]][0], entry.Locations[files[1]][0]})
+	}
+	slices.SortFunc(got, slices.Compare)
+	if want := [][]int{{2, 4, 2}, {2, 51, 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("counts and lines of the duplicates %v, want %v", got, want)
+	}
+	if !strings.Contains(warn.String(), "hashing all lines") {
+		t.Errorf("expected a mode switch warning, got %q", warn.String())
+	}
+}
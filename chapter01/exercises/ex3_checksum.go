@@ -0,0 +1,83 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Report checksums
+
+	To tell whether two runs found the same duplicates, e.g. to reuse a cached CI result, it is
+	enough to compare checksums of their reports. The checksum must not depend on anything that
+	varies between equal runs: the entries are put in text order and every list of line numbers
+	and offsets in ascending order before hashing, and JSON encoding already writes map keys in
+	order, so the map iteration order of the scan doesn't leak in. The order of the entries is
+	total: two entries tied on text, file and start are ordered by count and then by their
+	locations, so no tie is left to the order the scan produced them in.
+
+	A report cut short by the byte budget or estimated from a sample is not the same result as a
+	full count of the input, even when the entries happen to agree, so BudgetReached and
+	SampleRate are part of the canonical form and change the checksum.
+**/
+
+package exercises
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"slices"
+)
+
+// ReportChecksum returns the hex sha256 of the canonical form of r: equal for reports with the
+// same entries, whatever their order
+func (r *DuplicateReport) ReportChecksum() string {
+	canonical := DuplicateReport{
+		Threshold:     r.Threshold,
+		Entries:       make([]DuplicateEntry, len(r.Entries)),
+		BudgetReached: r.BudgetReached,
+		SampleRate:    r.SampleRate,
+	}
+	for i, entry := range r.Entries {
+		entry.Locations = sortedValues(entry.Locations)
+		entry.Offsets = sortedValues(entry.Offsets)
+		canonical.Entries[i] = entry
+	}
+	slices.SortFunc(canonical.Entries, func(a, b DuplicateEntry) int {
+		return cmp.Or(
+			cmp.Compare(a.Text, b.Text),
+			cmp.Compare(a.File, b.File),
+			cmp.Compare(a.Start, b.Start),
+			cmp.Compare(a.Count, b.Count),
+			compareLocations(a.Locations, b.Locations),
+		)
+	})
+
+	// A report is plain data, it always encodes
+	data, _ := json.Marshal(canonical)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// compareLocations orders sorted location lists by file name, then by the line numbers in each
+func compareLocations(a, b map[string][]int) int {
+	aFiles, bFiles := slices.Sorted(maps.Keys(a)), slices.Sorted(maps.Keys(b))
+	for i := 0; i < len(aFiles) && i < len(bFiles); i++ {
+		if c := cmp.Or(
+			cmp.Compare(aFiles[i], bFiles[i]),
+			slices.Compare(a[aFiles[i]], b[bFiles[i]]),
+		); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(aFiles), len(bFiles))
+}
+
+// sortedValues returns a copy of m with every slice sorted, leaving m as it is
+func sortedValues[T cmp.Ordered](m map[string][]T) map[string][]T {
+	if m == nil {
+		return nil
+	}
+	sorted := maps.Clone(m)
+	for k, v := range sorted {
+		sorted[k] = slices.Sorted(slices.Values(v))
+	}
+	return sorted
+}
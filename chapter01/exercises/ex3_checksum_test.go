@@ -0,0 +1,82 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Report checksums
+**/
+
+package exercises
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestReportChecksum(t *testing.T) {
+	a := &DuplicateReport{Threshold: 1, Entries: []DuplicateEntry{
+		{Text: "x", Count: 3, Locations: map[string][]int{"f": {1, 4}, "g": {2}}},
+		{Text: "y", Count: 2, Locations: map[string][]int{"f": {2, 3}}},
+	}}
+	b := &DuplicateReport{Threshold: 1, Entries: []DuplicateEntry{
+		{Text: "y", Count: 2, Locations: map[string][]int{"f": {3, 2}}},
+		{Text: "x", Count: 3, Locations: map[string][]int{"g": {2}, "f": {4, 1}}},
+	}}
+	if a.ReportChecksum() != b.ReportChecksum() {
+		t.Errorf("equal reports: checksums %s and %s", a.ReportChecksum(), b.ReportChecksum())
+	}
+	if !slices.Equal(b.Entries[0].Locations["f"], []int{3, 2}) || b.Entries[0].Text != "y" {
+		t.Error("ReportChecksum changed the report")
+	}
+
+	b.Entries[0].Count = 5
+	if a.ReportChecksum() == b.ReportChecksum() {
+		t.Error("different counts, same checksum")
+	}
+	b.Entries[0].Count = 2
+	b.Threshold = 2
+	if a.ReportChecksum() == b.ReportChecksum() {
+		t.Error("different thresholds, same checksum")
+	}
+	b.Threshold = 1
+
+	full := a.ReportChecksum()
+	a.BudgetReached = true
+	if a.ReportChecksum() == full {
+		t.Error("truncated report, same checksum as the full one")
+	}
+	a.BudgetReached = false
+	a.SampleRate = 0.5
+	if a.ReportChecksum() == full {
+		t.Error("sampled report, same checksum as the full one")
+	}
+}
+
+func TestReportChecksumTies(t *testing.T) {
+	// Entries tied on text, file and start, as the same text can be several entries
+	x1 := DuplicateEntry{Text: "x", Count: 2, Locations: map[string][]int{"f": {1, 2}}}
+	x2 := DuplicateEntry{Text: "x", Count: 2, Locations: map[string][]int{"g": {1, 2}}}
+	x3 := DuplicateEntry{Text: "x", Count: 3, Locations: map[string][]int{"f": {3, 4, 5}}}
+	a := &DuplicateReport{Threshold: 1, Entries: []DuplicateEntry{x1, x2, x3}}
+	b := &DuplicateReport{Threshold: 1, Entries: []DuplicateEntry{x3, x2, x1}}
+	c := &DuplicateReport{Threshold: 1, Entries: []DuplicateEntry{x2, x3, x1}}
+	if a.ReportChecksum() != b.ReportChecksum() || a.ReportChecksum() != c.ReportChecksum() {
+		t.Errorf("same entries in another order: checksums %s, %s and %s",
+			a.ReportChecksum(), b.ReportChecksum(), c.ReportChecksum())
+	}
+}
+
+func TestReportChecksumAcrossRuns(t *testing.T) {
+	dir := t.TempDir()
+	f := writeTestFile(t, dir, "a", "p\nq\np\nq\nr\np\n")
+	var sums []string
+	for range 5 {
+		report, err := DetectReportWith([]string{f}, WithParallelism(Parallelism{Files: 2, Hash: 4}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sums = append(sums, report.ReportChecksum())
+	}
+	for _, sum := range sums[1:] {
+		if sum != sums[0] {
+			t.Fatalf("checksums differ between runs: %v", sums)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Lines common to two files
+
+	CommonLines is the intersection of two files by line: every line that occurs in both, however
+	often, with its count in each. There is no threshold, a line seen once in each file is common.
+**/
+
+package exercises
+
+import (
+	"os"
+	"sort"
+)
+
+type CommonLine struct {
+	Text   string `json:"text"`
+	CountA int    `json:"count_a"`
+	CountB int    `json:"count_b"`
+}
+
+// CommonLines returns the lines found in both files, most frequent overall first, then by text
+func CommonLines(fileA, fileB string) ([]CommonLine, error) {
+	for _, f := range []string{fileA, fileB} {
+		// countLines would only print a message and carry on
+		if _, err := os.Stat(f); err != nil {
+			return nil, err
+		}
+	}
+	opts := defaultDupOptions(0)
+	// Count the files separately, the same file given twice is still compared with itself
+	countsA, countsB := countLines(opts, fileA), countLines(opts, fileB)
+
+	var common []CommonLine
+	for key, a := range countsA {
+		if b, ok := countsB[key]; ok {
+			common = append(common, CommonLine{Text: a.displayText(key), CountA: a.count, CountB: b.count})
+		}
+	}
+	sort.Slice(common, func(i, j int) bool {
+		ti, tj := common[i].CountA+common[i].CountB, common[j].CountA+common[j].CountB
+		if ti != tj {
+			return ti > tj
+		}
+		return common[i].Text < common[j].Text
+	})
+	return common, nil
+}
@@ -0,0 +1,34 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Lines common to two files
+**/
+
+package exercises
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCommonLines(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a", "shared\nonly a\nshared\nonce each\nonly a\nrepeated in b\n")
+	b := writeTestFile(t, dir, "b", "repeated in b\nonce each\nonly b\nshared\nrepeated in b\nrepeated in b\n")
+
+	got, err := CommonLines(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CommonLine{
+		{Text: "repeated in b", CountA: 1, CountB: 3},
+		{Text: "shared", CountA: 2, CountB: 1},
+		{Text: "once each", CountA: 1, CountB: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("common = %+v, want %+v", got, want)
+	}
+
+	if _, err := CommonLines(a, "does-not-exist"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
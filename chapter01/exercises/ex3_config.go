@@ -0,0 +1,118 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Config files
+
+	A CI job is easier to reproduce with its options in a file next to it than spread over a
+	command line. DetectConfigFile reads them from JSON and turns them into DetectOptions:
+
+		{"threshold": 2, "case_insensitive": true, "min_files": 2, "charset": "latin1"}
+
+	Settings left out keep their defaults. Options are applied in order, the last one setting a
+	value wins, so the options of the file go first and flags given on the command line after
+	them to override the file. A misspelled setting is an error rather than silently ignored.
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// DetectConfigFile reads the options of a Detect run from a JSON file, see above
+func DetectConfigFile(path string) ([]DetectOption, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Threshold             *int    `json:"threshold"`
+		Sorted                *bool   `json:"sorted"`
+		CaseInsensitive       *bool   `json:"case_insensitive"`
+		NormalizeUnicode      *bool   `json:"normalize_unicode"`
+		NormalizeNumbers      *bool   `json:"normalize_numbers"`
+		MinLineLength         int     `json:"min_line_length"`
+		HeadLimit             int     `json:"head_limit"`
+		ByteBudget            int64   `json:"byte_budget"`
+		SampleRate            float64 `json:"sample_rate"`
+		SampleSeed            *uint64 `json:"sample_seed"`
+		ExcludeFile           string  `json:"exclude_file"`
+		IgnoreRegex           string  `json:"ignore_regex"`
+		MinFiles              int     `json:"min_files"`
+		BlockLines            int     `json:"block_lines"`
+		CountOnly             *bool   `json:"count_only"`
+		CompactLocations      *bool   `json:"compact_locations"`
+		ContextLines          int     `json:"context_lines"`
+		ContinuousLineNumbers *bool   `json:"continuous_line_numbers"`
+		Charset               string  `json:"charset"`
+		Redact                string  `json:"redact"`
+		ParallelFiles         int     `json:"parallel_files"`
+		ParallelHash          int     `json:"parallel_hash"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var options []DetectOption
+	add := func(set bool, option DetectOption) {
+		if set {
+			options = append(options, option)
+		}
+	}
+	// Switches set false are applied too, turning off what an earlier option turned on
+	addSwitch := func(on *bool, option func(bool) DetectOption) {
+		if on != nil {
+			options = append(options, option(*on))
+		}
+	}
+	if file.Threshold != nil {
+		options = append(options, WithThreshold(*file.Threshold))
+	}
+	addSwitch(file.Sorted, WithSorted)
+	addSwitch(file.CaseInsensitive, WithCaseInsensitive)
+	addSwitch(file.NormalizeUnicode, WithNormalizeUnicode)
+	addSwitch(file.NormalizeNumbers, WithNormalizeNumbers)
+	add(file.MinLineLength > 0, WithMinLineLength(file.MinLineLength))
+	add(file.HeadLimit > 0, WithHeadLimit(file.HeadLimit))
+	add(file.ByteBudget > 0, WithByteBudget(file.ByteBudget))
+	add(file.SampleRate > 0, WithSampleRate(file.SampleRate))
+	if file.SampleSeed != nil {
+		options = append(options, WithSampleSeed(*file.SampleSeed))
+	}
+	add(file.ExcludeFile != "", WithExcludeFile(file.ExcludeFile))
+	add(file.MinFiles > 0, WithMinFiles(file.MinFiles))
+	add(file.BlockLines > 0, WithBlockLines(file.BlockLines))
+	addSwitch(file.CountOnly, WithCountOnly)
+	addSwitch(file.CompactLocations, WithCompactLocations)
+	add(file.ContextLines > 0, WithContextLines(file.ContextLines))
+	addSwitch(file.ContinuousLineNumbers, WithContinuousLineNumbers)
+	add(file.ParallelFiles > 0 || file.ParallelHash > 0,
+		WithParallelism(Parallelism{Files: file.ParallelFiles, Hash: file.ParallelHash}))
+	if file.Charset != "" {
+		enc, err := ParseCharset(file.Charset)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		options = append(options, WithCharset(enc))
+	}
+	if file.IgnoreRegex != "" {
+		re, err := regexp.Compile(file.IgnoreRegex)
+		if err != nil {
+			return nil, fmt.Errorf("%s: ignore_regex: %w", path, err)
+		}
+		options = append(options, WithIgnoreRegex(re))
+	}
+	if file.Redact != "" {
+		redact, err := ParseRedact(file.Redact)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		options = append(options, WithRedact(redact))
+	}
+	return options, nil
+}
@@ -0,0 +1,60 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Config files
+**/
+
+package exercises
+
+import (
+	"testing"
+)
+
+func TestDetectConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, "dups.json",
+		`{"threshold": 2, "case_insensitive": true, "min_files": 2, "charset": "utf-16le", "parallel_files": 3}`)
+
+	options, err := DetectConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A flag on the command line, after the file
+	d := newDetectOptions(append(options, WithThreshold(5))...)
+	if d.dup.threshold != 5 {
+		t.Errorf("threshold %d, want 5 from the flag", d.dup.threshold)
+	}
+	if !d.dup.caseInsensitive || d.dup.minFiles != 2 || d.dup.parallelFiles != 3 || d.dup.charset == nil {
+		t.Errorf("settings of the file lost: %+v", d.dup)
+	}
+	if d.dup.normalizeUnicode || d.dup.countOnly || d.sorted {
+		t.Errorf("settings left out of the file changed: %+v", d.dup)
+	}
+
+	d = newDetectOptions(options...)
+	if d.dup.threshold != 2 {
+		t.Errorf("without the flag: threshold %d, want 2 from the file", d.dup.threshold)
+	}
+
+	// A switch turned off by the flag after the file, or by the file after a default
+	if d := newDetectOptions(append(options, WithCaseInsensitive(false))...); d.dup.caseInsensitive {
+		t.Error("case_insensitive from the file not turned off by the flag")
+	}
+	off, err := DetectConfigFile(writeTestFile(t, dir, "off.json", `{"count_only": false}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := newDetectOptions(append([]DetectOption{WithCountOnly(true)}, off...)...); d.dup.countOnly {
+		t.Error("count_only false in the file didn't turn it off")
+	}
+
+	for _, bad := range []string{
+		`{"treshold": 2}`,
+		`{"threshold": "two"}`,
+		`{"charset": "klingon"}`,
+		`{"ignore_regex": "[0-9"}`,
+	} {
+		if _, err := DetectConfigFile(writeTestFile(t, dir, "bad.json", bad)); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Context lines
+
+	Like grep -C, contextLines shows the lines around every occurrence of a duplicate. Keeping a
+	window of neighbours for every line during the scan would cost memory for lines that turn
+	out not to be duplicates, so the context is read in a second pass instead, once the report
+	is known, and only for the files and line numbers it needs. That pass needs files it can open
+	again: stdin and archive members get no context, nor do continuous line numbers, which don't
+	say where in its file a line is. Redacted reports get none either, context being unredacted.
+**/
+
+package exercises
+
+import (
+	"fmt"
+	"io"
+	"slices"
+)
+
+// lineContext holds the text of the context lines, by file and line number
+type lineContext map[string]map[int]string
+
+// loadContext reads the lines within opts.contextLines of each location of the given lines
+func loadContext(counts map[string]lineData, lines []string, opts *dupOptions) lineContext {
+	if opts.continuousLineNumbers || opts.redact != nil {
+		return nil
+	}
+	wanted := make(map[string]map[int]bool)
+	for _, line := range lines {
+		for fileName, lineNums := range counts[line].locations {
+			if wanted[fileName] == nil {
+				wanted[fileName] = make(map[int]bool)
+			}
+			for _, n := range lineNums {
+				for i := n - opts.contextLines; i <= n+opts.contextLines; i++ {
+					wanted[fileName][i] = true
+				}
+			}
+		}
+	}
+
+	context := make(lineContext)
+	for fileName, want := range wanted {
+		if fileName == "stdin" {
+			continue
+		}
+		r, closer, err := openSourceWith(fileName, opts.openFile)
+		if err != nil {
+			continue
+		}
+		context[fileName] = make(map[int]string)
+		input := newLineScanner(opts.decode(r))
+		for n := 1; input.Scan(); n++ {
+			if want[n] {
+				context[fileName][n] = input.Text()
+			}
+		}
+		closer.Close()
+	}
+	return context
+}
+
+// print writes the context of the occurrences at lineNums in fileName, one block per run of
+// overlapping windows, blocks separated by "--". As in grep, ":" marks an occurrence and "-" a
+// line around it.
+func (c lineContext) print(w io.Writer, fileName string, lineNums []int, contextLines int) {
+	texts, ok := c[fileName]
+	if !ok {
+		return
+	}
+	occurrence := make(map[int]bool, len(lineNums))
+	for _, n := range lineNums {
+		occurrence[n] = true
+	}
+	last := 0 // last line printed
+	for _, n := range slices.Sorted(slices.Values(lineNums)) {
+		first := max(n-contextLines, last+1)
+		if last > 0 && first > last+1 {
+			fmt.Fprintln(w, "\t\t--")
+		}
+		for i := first; i <= n+contextLines; i++ {
+			text, ok := texts[i]
+			if !ok {
+				continue // before the start or past the end of the file
+			}
+			mark := "-"
+			if occurrence[i] {
+				mark = ":"
+			}
+			fmt.Fprintf(w, "\t\t%d%s %s\n", i, mark, text)
+			last = i
+		}
+	}
+}
@@ -0,0 +1,46 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Context lines
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestContextLines(t *testing.T) {
+	// dup at the start, in the middle and at the end
+	f := writeTestFile(t, t.TempDir(), "a", "dup\nl2\nl3\nl4\ndup\nl6\nl7\nl8\ndup\n")
+	opts := defaultDupOptions(1)
+	opts.contextLines = 1
+	counts := countLines(opts, f)
+
+	context := loadContext(counts, []string{"dup"}, &opts)
+	want := lineContext{f: {1: "dup", 2: "l2", 4: "l4", 5: "dup", 6: "l6", 8: "l8", 9: "dup"}}
+	if !reflect.DeepEqual(context, want) {
+		t.Errorf("context = %v, want %v", context, want)
+	}
+
+	var out bytes.Buffer
+	opts.out = &out
+	printCounts(counts, &opts, []string{f})
+	wantOut := "\tFileName: " + f + ", lineNums: [1 5 9]\n" +
+		"\t\t1: dup\n\t\t2- l2\n\t\t--\n" +
+		"\t\t4- l4\n\t\t5: dup\n\t\t6- l6\n\t\t--\n" +
+		"\t\t8- l8\n\t\t9: dup\n"
+	if !strings.Contains(out.String(), wantOut) {
+		t.Errorf("output:\n%s\nwant it to contain:\n%s", out.String(), wantOut)
+	}
+
+	// Windows that touch or overlap print as one block
+	out.Reset()
+	opts.contextLines = 2
+	printCounts(counts, &opts, []string{f})
+	if strings.Contains(out.String(), "\t\t--") || !strings.Contains(out.String(), "\t\t3- l3\n\t\t4- l4\n") {
+		t.Errorf("overlapping windows not merged:\n%s", out.String())
+	}
+}
@@ -0,0 +1,65 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Deduplicating a file in place
+
+	DeduplicateFile is awk '!seen[$0]++' > tmp && mv tmp file: it keeps the first occurrence of
+	every line, in the original order, and drops the rest. The new content goes to a temp file that
+	replaces the original only once complete, keeping its permissions. Kept lines are copied byte
+	for byte, line endings included. Only the keys of the lines seen are held in memory, hashed
+	like the counter's for lines of 32 bytes and more.
+**/
+
+package exercises
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// DeduplicateFile drops every repeat of an earlier line from the file at path, returning how many
+// lines were removed
+func DeduplicateFile(path string) (removed int, err error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	err = writeFileAtomicMode(path, info.Mode().Perm(), func(w io.Writer) error {
+		r := bufio.NewReader(in)
+		out := bufio.NewWriter(w)
+		seen := make(map[string]bool)
+		hasher := sha256Hasher{}
+		for {
+			line, err := r.ReadString('\n')
+			if line != "" {
+				key := getKey(strings.TrimSuffix(line, "\n"), hasher)
+				if seen[key] {
+					removed++
+				} else {
+					seen[key] = true
+					if _, err := out.WriteString(line); err != nil {
+						return err
+					}
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				return out.Flush()
+			}
+			if err != nil {
+				return err
+			}
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
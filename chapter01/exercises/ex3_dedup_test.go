@@ -0,0 +1,50 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Deduplicating a file in place
+**/
+
+package exercises
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDeduplicateFile(t *testing.T) {
+	long := strings.Repeat("long line ", 10)
+	f := writeTestFile(t, t.TempDir(), "a", "c\nb\nc\na\n"+long+"\nb\n\n"+long+"\n\nc")
+	if err := os.Chmod(f, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := DeduplicateFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 5 {
+		t.Errorf("removed %d lines, want 5", removed)
+	}
+	got, err := os.ReadFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// First occurrences in their original order; the final "c" has no newline but is still a repeat
+	if want := "c\nb\na\n" + long + "\n\n"; string(got) != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+	if info, err := os.Stat(f); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, %v, want the original 0600", info.Mode(), err)
+	}
+
+	// Already unique, nothing to do
+	if removed, err := DeduplicateFile(f); err != nil || removed != 0 {
+		t.Errorf("second pass removed %d, %v", removed, err)
+	}
+}
+
+func TestDeduplicateFileMissing(t *testing.T) {
+	if _, err := DeduplicateFile("does-not-exist"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,74 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Graphviz output
+
+	Which files share content is easier to see than to read off a list of lines. FormatDOT draws
+	the report as an undirected graph for Graphviz: a node per file, and an edge between two files
+	weighted by the number of duplicate lines found in both, the locations maps of the entries
+	telling which files those are. Files only repeating lines within themselves are nodes without
+	edges. Sorted input runs stay within one file, so they draw no edges either.
+
+		go run . -o dups.dot -format dot && dot -Tsvg dups.dot > dups.svg
+**/
+
+package exercises
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+type filePair struct {
+	a, b string // a < b
+}
+
+func (r *DuplicateReport) writeDOT(w io.Writer) error {
+	files := make(map[string]bool)
+	shared := make(map[filePair]int)
+	for _, entry := range r.Entries {
+		names := entry.fileNames()
+		if entry.File != "" {
+			names = []string{entry.File}
+		}
+		for i, a := range names {
+			files[a] = true
+			for _, b := range names[i+1:] {
+				shared[filePair{a, b}]++
+			}
+		}
+	}
+
+	nodes := make([]string, 0, len(files))
+	for name := range files {
+		nodes = append(nodes, name)
+	}
+	sort.Strings(nodes)
+	pairs := make([]filePair, 0, len(shared))
+	for pair := range shared {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].a != pairs[j].a {
+			return pairs[i].a < pairs[j].a
+		}
+		return pairs[i].b < pairs[j].b
+	})
+
+	if _, err := fmt.Fprintln(w, "graph duplicates {"); err != nil {
+		return err
+	}
+	for _, name := range nodes {
+		if _, err := fmt.Fprintf(w, "\t%q;\n", name); err != nil {
+			return err
+		}
+	}
+	for _, pair := range pairs {
+		n := shared[pair]
+		if _, err := fmt.Fprintf(w, "\t%q -- %q [weight=%d, label=\"%d\"];\n", pair.a, pair.b, n, n); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
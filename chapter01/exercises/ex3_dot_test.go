@@ -0,0 +1,49 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Graphviz output
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteDOT(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a", "shared 1\nshared 2\nonly a\nonly a\n")
+	b := writeTestFile(t, dir, "b", "shared 2\nshared 1\n")
+	c := writeTestFile(t, dir, "c", "unrelated\nshared 1\n")
+	report, err := DetectReport(1, false, a, b, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	format, err := ParseOutputFormat("dot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := report.Write(&out, format); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"graph duplicates {\n",
+		"\t\"" + a + "\";\n",
+		"\t\"" + b + "\";\n",
+		"\t\"" + c + "\";\n",
+		"\t\"" + a + "\" -- \"" + b + "\" [weight=2, label=\"2\"];\n",
+		"\t\"" + a + "\" -- \"" + c + "\" [weight=1, label=\"1\"];\n",
+		"\t\"" + b + "\" -- \"" + c + "\" [weight=1, label=\"1\"];\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if n := strings.Count(got, " -- "); n != 3 {
+		t.Errorf("%d edges, want 3:\n%s", n, got)
+	}
+}
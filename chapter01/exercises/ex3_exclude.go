@@ -0,0 +1,35 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Excluding known lines
+
+	Some lines always repeat (license headers, separators) and only hide the interesting
+	duplicates. The excludeFile option names a file of such lines. Each is keyed exactly like an
+	input line, so with caseInsensitive or a preprocess hook an excluded line matches the same
+	variants the counter would have merged with it.
+**/
+
+package exercises
+
+import "fmt"
+
+func loadExcludeSet(path string, opts *dupOptions) (map[string]bool, error) {
+	r, closer, err := openSourceWith(path, opts.openFile)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	input := newLineScanner(r)
+
+	keyOpts := *opts
+	keyOpts.exclude = nil
+	exclude := make(map[string]bool)
+	for input.Scan() {
+		if rawLineDatum, ok := newRawLine(path, 0, input.Text(), &keyOpts, false); ok {
+			exclude[rawLineDatum.lineText] = true
+		}
+	}
+	if err := input.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return exclude, nil
+}
@@ -0,0 +1,117 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Following a growing file
+
+	Like tail -f, Follow keeps a file open and counts lines as they are appended, polling for new
+	data at a fixed interval (no fsnotify, a stat per poll is cheap and works on every filesystem).
+	Existing content is counted first. A line is only counted once its newline has been written.
+	When the file shrinks it was truncated and is read again from the start; when the path now
+	names a different file it was rotated, and the new file is opened. Either way counts carry on,
+	line numbers restart.
+**/
+
+package exercises
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// How often a followed file is checked for new lines
+const followPoll = 250 * time.Millisecond
+
+// Follow counts the lines of path as they are written, until ctx is done
+func (lc *LiveCounter) Follow(ctx context.Context, path string, poll time.Duration) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { file.Close() }()
+
+	r := bufio.NewReader(file)
+	var offset int64 // bytes of complete lines read
+	partial := ""    // start of a line still being written
+	lineNum := 0
+	for {
+		line, err := r.ReadString('\n')
+		if err == nil {
+			offset += int64(len(line))
+			lineNum++
+			text := strings.TrimSuffix(strings.TrimSuffix(partial+line, "\n"), "\r")
+			partial = ""
+			if rawLineDatum, ok := newRawLine(path, lineNum, text, &lc.opts, false); ok {
+				lc.add(rawLineDatum)
+			}
+			continue
+		}
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+		partial += line
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(poll):
+		}
+
+		reopen, err := followedFileChanged(file, path, offset+int64(len(partial)))
+		if err != nil {
+			// Mid-rotation the path may briefly not exist, try again next poll
+			continue
+		}
+		if reopen {
+			newFile, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			file.Close()
+			file = newFile
+			r.Reset(file)
+			offset, partial, lineNum = 0, "", 0
+		}
+	}
+}
+
+// followedFileChanged says whether path was truncated below read bytes or now names another file
+func followedFileChanged(file *os.File, path string, read int64) (bool, error) {
+	pathInfo, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	return !os.SameFile(pathInfo, fileInfo) || pathInfo.Size() < read, nil
+}
+
+// FollowFile follows path, printing the top duplicates every interval, until ctx is done
+func FollowFile(ctx context.Context, threshold int, path string, every time.Duration) error {
+	if err := checkThreshold(threshold); err != nil {
+		return err
+	}
+	lc := NewLiveCounter(threshold)
+	done := make(chan error, 1)
+	go func() { done <- lc.Follow(ctx, path, followPoll) }()
+
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-ticker.C:
+			fmt.Printf("---- %s\n", time.Now().Format(time.TimeOnly))
+			for _, entry := range lc.Top(10) {
+				fmt.Printf("%d\t%s\n", entry.Count, entry.Text)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Following a growing file
+**/
+
+package exercises
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// waitForTop waits for the live counts to reach want, as entries with only Text and Count
+func waitForTop(t *testing.T, lc *LiveCounter, want []DuplicateEntry) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := lc.Top(10)
+		if reflect.DeepEqual(got, want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("top = %+v, want %+v", got, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func appendTo(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, "app.log", "a\na\n")
+
+	lc := NewLiveCounter(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	followed := make(chan error)
+	go func() { followed <- lc.Follow(ctx, path, 5*time.Millisecond) }()
+
+	waitForTop(t, lc, []DuplicateEntry{{Text: "a", Count: 2}})
+
+	// Only the new lines are counted, the half written "b" once it's complete
+	appendTo(t, path, "a\nb\nb")
+	waitForTop(t, lc, []DuplicateEntry{{Text: "a", Count: 3}})
+	appendTo(t, path, "\n")
+	waitForTop(t, lc, []DuplicateEntry{{Text: "a", Count: 3}, {Text: "b", Count: 2}})
+
+	// Truncated and rewritten: read from the start again, earlier counts kept
+	if err := os.WriteFile(path, []byte("c\nc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitForTop(t, lc, []DuplicateEntry{{Text: "a", Count: 3}, {Text: "b", Count: 2}, {Text: "c", Count: 2}})
+
+	// Rotated: the path now names a new file
+	if err := os.Rename(path, filepath.Join(dir, "app.log.1")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, "app.log", "d\nd\nd\n")
+	waitForTop(t, lc, []DuplicateEntry{{Text: "a", Count: 3}, {Text: "d", Count: 3}, {Text: "b", Count: 2}, {Text: "c", Count: 2}})
+
+	cancel()
+	if err := <-followed; err != nil {
+		t.Errorf("Follow returned %v", err)
+	}
+}
+
+func TestFollowMissingFile(t *testing.T) {
+	if err := NewLiveCounter(1).Follow(context.Background(), "does-not-exist", time.Millisecond); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
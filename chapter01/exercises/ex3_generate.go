@@ -0,0 +1,40 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Reproducible test input
+
+	GenerateInput writes lines drawn uniformly from a set of distinct templates with a seeded
+	math/rand, so tests and benchmarks get the same input on every run and can set how much of it
+	repeats: lines/distinct occurrences per template on average. Every third template is longer
+	than maxRawKeyLen, so both the raw and the hashed key paths get exercised.
+**/
+
+package exercises
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+)
+
+func GenerateInput(w io.Writer, lines int, distinct int, seed int64) error {
+	if distinct <= 0 {
+		return fmt.Errorf("need at least one distinct line, got %d", distinct)
+	}
+	templates := make([]string, distinct)
+	for i := range templates {
+		templates[i] = fmt.Sprintf("event %d", i)
+		if i%3 == 0 {
+			templates[i] += " " + strings.Repeat("payload ", 1+i%7)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	bw := bufio.NewWriter(w)
+	for i := 0; i < lines; i++ {
+		bw.WriteString(templates[rng.Intn(distinct)])
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,100 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Reproducible test input
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateInputIsReproducible(t *testing.T) {
+	var first, second, other bytes.Buffer
+	for _, gen := range []struct {
+		buf  *bytes.Buffer
+		seed int64
+	}{{&first, 42}, {&second, 42}, {&other, 7}} {
+		if err := GenerateInput(gen.buf, 1000, 50, gen.seed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("the same seed gave different output")
+	}
+	if bytes.Equal(first.Bytes(), other.Bytes()) {
+		t.Error("different seeds gave the same output")
+	}
+	if n := strings.Count(first.String(), "\n"); n != 1000 {
+		t.Errorf("got %d lines, want 1000", n)
+	}
+
+	f := writeTestFile(t, t.TempDir(), "generated", first.String())
+	counts := countLines(defaultDupOptions(1), f)
+	if len(counts) > 50 {
+		t.Errorf("got %d distinct lines, want at most 50", len(counts))
+	}
+	total := 0
+	for _, lineDatum := range counts {
+		total += lineDatum.count
+	}
+	if total != 1000 {
+		t.Errorf("counted %d lines, want 1000", total)
+	}
+
+	if err := GenerateInput(&other, 10, 0, 1); err == nil {
+		t.Error("expected an error for no distinct lines")
+	}
+}
+
+func BenchmarkCountLines(b *testing.B) {
+	var input bytes.Buffer
+	if err := GenerateInput(&input, 100000, 5000, 1); err != nil {
+		b.Fatal(err)
+	}
+	f := filepath.Join(b.TempDir(), "generated")
+	if err := os.WriteFile(f, input.Bytes(), 0o644); err != nil {
+		b.Fatal(err)
+	}
+	opts := defaultDupOptions(1)
+	b.SetBytes(int64(input.Len()))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		countLines(opts, f)
+	}
+}
+
+// BenchmarkLineBuffer measures the capacity of the channel into the counter on several files
+// read at once, the case where readers contend for the counter
+func BenchmarkLineBuffer(b *testing.B) {
+	dir := b.TempDir()
+	var files []string
+	var size int64
+	for i := 0; i < 8; i++ {
+		var input bytes.Buffer
+		if err := GenerateInput(&input, 50000, 5000, int64(i)); err != nil {
+			b.Fatal(err)
+		}
+		f := filepath.Join(dir, fmt.Sprintf("generated%d", i))
+		if err := os.WriteFile(f, input.Bytes(), 0o644); err != nil {
+			b.Fatal(err)
+		}
+		files = append(files, f)
+		size += int64(input.Len())
+	}
+	for _, capacity := range []int{0, 64, defaultLineBuffer, 1024} {
+		b.Run(fmt.Sprintf("cap%d", capacity), func(b *testing.B) {
+			opts := defaultDupOptions(1)
+			opts.lineBuffer = capacity
+			b.SetBytes(size)
+			for i := 0; i < b.N; i++ {
+				countLines(opts, files...)
+			}
+		})
+	}
+}
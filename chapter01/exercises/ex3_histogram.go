@@ -0,0 +1,77 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Frequency histogram
+
+	For a quick sense of how redundant the input is, the histogram mode prints how many distinct
+	lines were seen 2 times, 3-5 times, 6-10 times and 11 or more times, optionally as a bar chart.
+	The buckets are given by their lower bounds, each running up to the next one; lines seen fewer
+	times than the first bound are left out. The threshold plays no part here.
+**/
+
+package exercises
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Buckets 2, 3-5, 6-10 and 11+
+var defaultHistogramBounds = []int{2, 3, 6, 11}
+
+const histogramBarWidth = 50
+
+type histogramBucket struct {
+	low, high int // high is 0 for the last, open ended, bucket
+	lines     int // distinct lines with a count in [low, high]
+}
+
+func (b histogramBucket) label() string {
+	switch {
+	case b.high == 0:
+		return strconv.Itoa(b.low) + "+"
+	case b.high == b.low:
+		return strconv.Itoa(b.low)
+	}
+	return fmt.Sprintf("%d-%d", b.low, b.high)
+}
+
+// countHistogram tallies the distinct lines into buckets starting at the given, increasing, bounds
+func countHistogram(counts map[string]lineData, bounds []int) []histogramBucket {
+	buckets := make([]histogramBucket, len(bounds))
+	for i, low := range bounds {
+		buckets[i].low = low
+		if i+1 < len(bounds) {
+			buckets[i].high = bounds[i+1] - 1
+		}
+	}
+	for _, lineDatum := range counts {
+		// Few buckets, a linear scan from the top is fine
+		for i := len(buckets) - 1; i >= 0; i-- {
+			if lineDatum.count >= buckets[i].low {
+				buckets[i].lines++
+				break
+			}
+		}
+	}
+	return buckets
+}
+
+func printHistogram(w io.Writer, buckets []histogramBucket, bars bool) {
+	most := 0
+	for _, b := range buckets {
+		most = max(most, b.lines)
+	}
+	for _, b := range buckets {
+		if !bars {
+			fmt.Fprintf(w, "%s\t%d\n", b.label(), b.lines)
+			continue
+		}
+		width := 0
+		if most > 0 {
+			width = (b.lines*histogramBarWidth + most - 1) / most
+		}
+		fmt.Fprintf(w, "%-8s %8d %s\n", b.label(), b.lines, strings.Repeat("#", width))
+	}
+}
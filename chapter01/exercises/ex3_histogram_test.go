@@ -0,0 +1,55 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Frequency histogram
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	// Distinct lines seen 1, 2, 2, 3, 5, 6, 10, 11 and 40 times
+	var sb strings.Builder
+	for i, n := range []int{1, 2, 2, 3, 5, 6, 10, 11, 40} {
+		sb.WriteString(strings.Repeat(fmt.Sprintf("line %d\n", i), n))
+	}
+	f := writeTestFile(t, t.TempDir(), "a", sb.String())
+	counts := countLines(defaultDupOptions(1), f)
+
+	got := countHistogram(counts, defaultHistogramBounds)
+	want := []histogramBucket{{2, 2, 2}, {3, 5, 2}, {6, 10, 2}, {11, 0, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("default buckets = %v, want %v", got, want)
+	}
+
+	got = countHistogram(counts, []int{1, 10})
+	want = []histogramBucket{{1, 9, 6}, {10, 0, 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("custom buckets = %v, want %v", got, want)
+	}
+
+	var out bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.histogram = true
+	opts.out = &out
+	printCounts(counts, &opts, []string{f})
+	if want := "2\t2\n3-5\t2\n6-10\t2\n11+\t2\n"; out.String() != want {
+		t.Errorf("histogram:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	opts.histogramBounds = []int{1, 10}
+	opts.histogramBars = true
+	printCounts(counts, &opts, []string{f})
+	want2 := "1-9             6 " + strings.Repeat("#", 50) + "\n" +
+		"10+             3 " + strings.Repeat("#", 25) + "\n"
+	if out.String() != want2 {
+		t.Errorf("bar chart:\n%s\nwant:\n%s", out.String(), want2)
+	}
+}
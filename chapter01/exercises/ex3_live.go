@@ -0,0 +1,91 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Live input from a socket
+
+	For live log ingestion a LiveCounter accepts connections, typically on a Unix domain socket,
+	and counts lines as they arrive, through the same scanning path as files. Counts accumulate
+	across connections for the life of the LiveCounter, so a client that disconnects and comes back
+	just carries on; each connection is its own "file" (conn-1, conn-2, ...) for line numbers.
+	Top gives the current duplicates at any time.
+**/
+
+package exercises
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+type LiveCounter struct {
+	opts    dupOptions
+	conns   atomic.Int64 // connections so far, names them
+	mu      sync.Mutex
+	counter *dupCounter
+}
+
+func NewLiveCounter(threshold int) *LiveCounter {
+	lc := &LiveCounter{opts: defaultDupOptions(threshold)}
+	// The input never ends, so only counts are kept: memory grows with the distinct lines, not
+	// with every line read
+	lc.opts.countOnly = true
+	lc.counter = newDupCounter(&lc.opts)
+	return lc
+}
+
+// ListenUnix listens on a Unix domain socket at path, replacing a stale socket file left behind
+// by an earlier run
+func ListenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	return net.Listen("unix", path)
+}
+
+// Serve scans every connection accepted on l until l is closed
+func (lc *LiveCounter) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go lc.ScanConn(conn)
+	}
+}
+
+// ScanConn counts the lines read from conn until the client disconnects, then closes it
+func (lc *LiveCounter) ScanConn(conn net.Conn) {
+	defer conn.Close()
+	name := fmt.Sprintf("conn-%d", lc.conns.Add(1))
+
+	// No binary sniffing here: peeking would wait for a few KB that may never come
+	scanLines(name, newLineScanner(conn), &lc.opts, false, func(line scannedLine) {
+		if rawLineDatum, ok := line.key(&lc.opts); ok {
+			lc.add(rawLineDatum)
+		}
+	})
+}
+
+func (lc *LiveCounter) add(rawLineDatum rawLineData) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.counter.add(rawLineDatum)
+}
+
+// Top returns up to n of the current duplicates, most frequent first. Entries carry no
+// locations, none are kept.
+func (lc *LiveCounter) Top(n int) []DuplicateEntry {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	entries := newDuplicateReport(lc.counter.counts, &lc.opts).Entries
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
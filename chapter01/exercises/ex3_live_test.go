@@ -0,0 +1,89 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Live input from a socket
+**/
+
+package exercises
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLiveCounterUnixSocket(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, t.TempDir() can be longer
+	dir, err := os.MkdirTemp("", "live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	l, err := ListenUnix(filepath.Join(dir, "dup.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lc := NewLiveCounter(1)
+	served := make(chan error)
+	go func() { served <- lc.Serve(l) }()
+
+	send := func(lines string) {
+		conn, err := net.Dial("unix", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprint(conn, lines)
+		conn.Close()
+	}
+	send("GET /\nGET /health\nGET /\n")
+	// A client reconnecting carries on with the same counts
+	send("GET /\nGET /health\nPOST /login\n")
+
+	want := []DuplicateEntry{{Text: "GET /", Count: 3}, {Text: "GET /health", Count: 2}}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := lc.Top(10)
+		if reflect.DeepEqual(got, want) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("top = %+v, want %+v", got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := lc.Top(1); len(got) != 1 || got[0].Text != "GET /" {
+		t.Errorf("top 1 = %+v", got)
+	}
+
+	l.Close()
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned %v after Close", err)
+	}
+}
+
+func TestLiveCounterKeepsNoLocations(t *testing.T) {
+	lc := NewLiveCounter(1)
+	client, conn := net.Pipe()
+	done := make(chan bool)
+	go func() {
+		lc.ScanConn(conn)
+		done <- true
+	}()
+	for i := range 100000 {
+		fmt.Fprintf(client, "line %d\n", i%10)
+	}
+	client.Close()
+	<-done
+
+	if got := lc.Top(1); len(got) != 1 || got[0].Count != 10000 {
+		t.Fatalf("top 1 = %+v, want a line 10000 times", got)
+	}
+	for key, lineDatum := range lc.counter.counts {
+		if len(lineDatum.locations) != 0 {
+			t.Fatalf("%q kept %d files of locations", key, len(lineDatum.locations))
+		}
+	}
+}
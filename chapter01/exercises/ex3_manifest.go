@@ -0,0 +1,49 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Reading the file list from a manifest
+
+	Thousands of files don't fit comfortably on a command line, so like tar -T and rsync
+	--files-from the file list can come from a manifest: one path per line, blank lines and lines
+	starting with '#' ignored. Paths are taken as is, relative ones are relative to the working
+	directory rather than to the manifest.
+**/
+
+package exercises
+
+import (
+	"fmt"
+	"strings"
+)
+
+func ReadManifest(manifest string) ([]string, error) {
+	input, closer, err := openLineSource(manifest)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	var files []string
+	for input.Scan() {
+		path := strings.TrimSpace(input.Text())
+		if path == "" || strings.HasPrefix(path, "#") {
+			continue
+		}
+		files = append(files, path)
+	}
+	return files, input.Err()
+}
+
+// DupDetectManifest is DupDetectFiles over the files listed in manifest
+func DupDetectManifest(threshold int, sorted bool, manifest string) {
+	files, err := ReadManifest(manifest)
+	if err != nil {
+		fmt.Printf("Error in reading manifest %s: %s\n", manifest, err)
+		return
+	}
+	if len(files) == 0 {
+		// DupDetectFiles would fall back to stdin
+		fmt.Printf("Manifest %s lists no files\n", manifest)
+		return
+	}
+	DupDetectFiles(threshold, sorted, files...)
+}
@@ -0,0 +1,43 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Reading the file list from a manifest
+**/
+
+package exercises
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadManifest(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a.log", "shared\n")
+	b := writeTestFile(t, dir, "b.log", "shared\n")
+	skipped := writeTestFile(t, dir, "skipped.log", "shared\n")
+	c := writeTestFile(t, dir, "c.log", "shared\n")
+	manifest := writeTestFile(t, dir, "manifest.txt",
+		"# inputs for the nightly run\n"+a+"\n\n  "+b+"  \n#"+skipped+"\n"+c+"\n")
+
+	files, err := ReadManifest(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{a, b, c}; !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+
+	counts := countLines(defaultDupOptions(1), files...)
+	if got := counts["shared"].count; got != 3 {
+		t.Errorf("shared count = %d, want 3", got)
+	}
+	if _, ok := counts["shared"].locations[skipped]; ok {
+		t.Error("commented out file was scanned")
+	}
+}
+
+func TestReadManifestMissing(t *testing.T) {
+	if _, err := ReadManifest("does-not-exist"); err == nil {
+		t.Error("expected an error for a missing manifest")
+	}
+}
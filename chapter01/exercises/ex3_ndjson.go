@@ -0,0 +1,71 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	NDJSON output
+
+	For ingestion into ELK and the like, NDJSON has one flat JSON object per location instead of a
+	nested report: {"text":...,"count":...,"file":...,"line":...}, plus "offset" when offsets were
+	recorded. A sorted run gives a record for every line in it. WriteNDJSON takes its entries from
+	the streaming API, so records go out as entries become final and the whole report never has to
+	be held in memory.
+**/
+
+package exercises
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+type ndjsonRecord struct {
+	Text   string `json:"text"`
+	Count  int    `json:"count"`
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Offset *int64 `json:"offset,omitempty"`
+}
+
+// WriteNDJSON writes a record per location of every entry received, until entries is closed, and
+// returns the error from errs if any
+func WriteNDJSON(w io.Writer, entries <-chan DuplicateEntry, errs <-chan error) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	var writeErr error
+	for entry := range entries {
+		if writeErr == nil {
+			writeErr = entry.writeNDJSON(enc)
+		}
+		// On a write error keep draining, so the producer isn't left blocked
+	}
+	if err := <-errs; err != nil {
+		return err
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	return bw.Flush()
+}
+
+func (entry DuplicateEntry) writeNDJSON(enc *json.Encoder) error {
+	if entry.File != "" {
+		for line := entry.Start; line <= entry.End; line++ {
+			if err := enc.Encode(ndjsonRecord{Text: entry.Text, Count: entry.Count, File: entry.File, Line: line}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, fileName := range entry.fileNames() {
+		offsets := entry.Offsets[fileName]
+		for i, line := range entry.Locations[fileName] {
+			record := ndjsonRecord{Text: entry.Text, Count: entry.Count, File: fileName, Line: line}
+			if i < len(offsets) {
+				record.Offset = &offsets[i]
+			}
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	NDJSON output
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteNDJSON(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a", "x\ny\nx\n")
+	b := writeTestFile(t, dir, "b", "x\nz\n")
+
+	var out bytes.Buffer
+	entries, errs := StreamDuplicates(context.Background(), 1, a, b)
+	if err := WriteNDJSON(&out, entries, errs); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []ndjsonRecord
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		var record ndjsonRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("line %q doesn't parse on its own: %v", line, err)
+		}
+		got = append(got, record)
+	}
+	want := []ndjsonRecord{
+		{Text: "x", Count: 3, File: a, Line: 1},
+		{Text: "x", Count: 3, File: a, Line: 3},
+		{Text: "x", Count: 3, File: b, Line: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("records = %+v, want %+v", got, want)
+	}
+}
+
+func TestNDJSONSortedAndOffsets(t *testing.T) {
+	var out bytes.Buffer
+	report := &DuplicateReport{Entries: []DuplicateEntry{
+		{Text: "run", Count: 2, File: "sorted", Start: 4, End: 5},
+		{Text: "x", Count: 2, Locations: map[string][]int{"a": {1, 3}}, Offsets: map[string][]int64{"a": {0, 4}}},
+	}}
+	if err := report.Write(&out, FormatNDJSON); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"text":"run","count":2,"file":"sorted","line":4}
+{"text":"run","count":2,"file":"sorted","line":5}
+{"text":"x","count":2,"file":"a","line":1,"offset":0}
+{"text":"x","count":2,"file":"a","line":3,"offset":4}
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,320 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Options
+
+	Every feature of the detector used to mean another parameter or another DupDetect variant.
+	Detect and DetectReportWith take functional options instead, so a new feature is a new
+	With... function and no caller breaks. Anything not set keeps the default of DupDetectFiles:
+	threshold 1, unsorted input, exact comparison, output to stdout. Switches take a bool, so a later
+	option can turn off what an earlier one turned on, as flags do over a config file.
+**/
+
+package exercises
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"golang.org/x/text/encoding"
+)
+
+// DetectOptions is the configuration of a Detect run, built from DetectOption values
+type DetectOptions struct {
+	dup    dupOptions
+	sorted bool
+}
+
+type DetectOption func(*DetectOptions)
+
+func newDetectOptions(options ...DetectOption) DetectOptions {
+	d := DetectOptions{dup: defaultDupOptions(1)}
+	for _, option := range options {
+		option(&d)
+	}
+	// Options are reused across runs, the bytes used are counted afresh for each
+	d.dup.byteBudget = newByteBudget(d.dup.byteLimit)
+	return d
+}
+
+// WithThreshold reports lines seen more than n times: 0 reports every line, 1 (the default) the
+// duplicates. A negative n is an error.
+func WithThreshold(n int) DetectOption {
+	return func(d *DetectOptions) { d.dup.threshold = n }
+}
+
+// WithSorted treats the input as one sorted file, reporting runs of lines
+func WithSorted(on bool) DetectOption {
+	return func(d *DetectOptions) { d.sorted = on }
+}
+
+// WithCaseInsensitive compares lines ignoring case
+func WithCaseInsensitive(on bool) DetectOption {
+	return func(d *DetectOptions) { d.dup.caseInsensitive = on }
+}
+
+// WithNormalizeUnicode compares lines in NFC, so composed and decomposed forms match
+func WithNormalizeUnicode(on bool) DetectOption {
+	return func(d *DetectOptions) { d.dup.normalizeUnicode = on }
+}
+
+// WithNormalizeNumbers compares lines with every run of digits replaced by "#"
+func WithNormalizeNumbers(on bool) DetectOption {
+	return func(d *DetectOptions) { d.dup.normalizeNumbers = on }
+}
+
+// WithRedact reports every line as redact returns it, e.g. HashLine or MaskLine
+func WithRedact(redact func(string) string) DetectOption {
+	return func(d *DetectOptions) { d.dup.redact = redact }
+}
+
+// WithPreprocess transforms every line before it is compared, e.g. to mask timestamps
+func WithPreprocess(fn func(string) string) DetectOption {
+	return func(d *DetectOptions) { d.dup.preprocess = fn }
+}
+
+// WithIgnoreRegex cuts the matches of re out of every line before it is compared, still
+// reporting lines as read
+func WithIgnoreRegex(re *regexp.Regexp) DetectOption {
+	return func(d *DetectOptions) { d.dup.ignoreRegex = re }
+}
+
+// WithMinLineLength skips lines shorter than n runes
+func WithMinLineLength(n int) DetectOption {
+	return func(d *DetectOptions) { d.dup.minLineLength = n }
+}
+
+// WithHeadLimit only scans the first n lines of each file
+func WithHeadLimit(n int) DetectOption {
+	return func(d *DetectOptions) { d.dup.headLimit = n }
+}
+
+// WithByteBudget stops scanning once n bytes have been scanned across all files, leaving a
+// partial report with BudgetReached set
+func WithByteBudget(n int64) DetectOption {
+	return func(d *DetectOptions) { d.dup.byteLimit = n }
+}
+
+// WithSampleRate counts a fraction rate of the lines, reporting the counts scaled by 1/rate as
+// estimates; rate 1 counts every line
+func WithSampleRate(rate float64) DetectOption {
+	return func(d *DetectOptions) { d.dup.sampleRate = rate }
+}
+
+// WithSampleSeed seeds the draw of WithSampleRate, the same seed sampling the same lines
+func WithSampleSeed(seed uint64) DetectOption {
+	return func(d *DetectOptions) { d.dup.sampleSeed = seed }
+}
+
+// WithExcludeFile ignores the lines found in path
+func WithExcludeFile(path string) DetectOption {
+	return func(d *DetectOptions) { d.dup.excludeFile = path }
+}
+
+// WithParallelism bounds the readers and hash workers of the scan
+func WithParallelism(p Parallelism) DetectOption {
+	return func(d *DetectOptions) { p.apply(&d.dup) }
+}
+
+// WithMinFiles only reports lines found in at least n distinct files
+func WithMinFiles(n int) DetectOption {
+	return func(d *DetectOptions) { d.dup.minFiles = n }
+}
+
+// WithLineBuffer sets how many keyed lines may wait for the counter, 0 for none
+func WithLineBuffer(n int) DetectOption {
+	return func(d *DetectOptions) { d.dup.lineBuffer = n }
+}
+
+// WithBlockLines finds repeated blocks of n consecutive lines rather than repeated lines
+func WithBlockLines(n int) DetectOption {
+	return func(d *DetectOptions) { d.dup.blockLines = n }
+}
+
+// WithMaxInFlightLines blocks the readers while n lines are read but not counted yet
+func WithMaxInFlightLines(n int) DetectOption {
+	return func(d *DetectOptions) { d.dup.maxInFlightLines = n }
+}
+
+// WithGroupByFile prints the duplicates under each file they occur in, files in the order given
+func WithGroupByFile(on bool) DetectOption {
+	return func(d *DetectOptions) { d.dup.groupByFile = on }
+}
+
+// WithPerFile counts each file on its own, so a line repeated only across files isn't reported
+func WithPerFile(on bool) DetectOption {
+	return func(d *DetectOptions) { d.dup.perFile = on }
+}
+
+// WithCollisionCheck keeps the first full line of every hashed key and warns when a different
+// line hashes to it
+func WithCollisionCheck(on bool) DetectOption {
+	return func(d *DetectOptions) { d.dup.checkCollisions = on }
+}
+
+// WithMaxKeyBytes hashes every line from the point the distinct keys take more than n bytes, 0
+// for no cap
+func WithMaxKeyBytes(n int) DetectOption {
+	return func(d *DetectOptions) { d.dup.maxKeyBytes = n }
+}
+
+// WithMaxDisplayWidth cuts reported lines to n runes, 0 printing them whole
+func WithMaxDisplayWidth(n int) DetectOption {
+	return func(d *DetectOptions) { d.dup.maxDisplayWidth = n }
+}
+
+// WithOffsets also records the byte offset each occurrence starts at in its file
+func WithOffsets(on bool) DetectOption {
+	return func(d *DetectOptions) { d.dup.withOffsets = on }
+}
+
+// WithMinPerFileCount reports lines occurring at least n times within one file, whatever the
+// threshold says
+func WithMinPerFileCount(n int) DetectOption {
+	return func(d *DetectOptions) { d.dup.minPerFileCount = n }
+}
+
+// WithLocationsCap records at most n locations per line, still counting the others
+func WithLocationsCap(n int) DetectOption {
+	return func(d *DetectOptions) { d.dup.locationsCap = n }
+}
+
+// WithOrder reports lines in order, e.g. one from ParseLineOrder, rather than most frequent first
+func WithOrder(order LineOrder) DetectOption {
+	return func(d *DetectOptions) { d.dup.order = order }
+}
+
+// WithCasingBreakdown also counts each casing of a line folded by WithCaseInsensitive
+func WithCasingBreakdown(on bool) DetectOption {
+	return func(d *DetectOptions) { d.dup.casingBreakdown = on }
+}
+
+// WithHashBinary scans files that look binary rather than skipping them, reporting their lines
+// by hash and length
+func WithHashBinary(on bool) DetectOption {
+	return func(d *DetectOptions) {
+		d.dup.binaryInput = skipBinary
+		if on {
+			d.dup.binaryInput = hashBinary
+		}
+	}
+}
+
+// WithHistogram prints how many lines fall in each count bucket rather than the lines, buckets
+// starting at bounds (2, 3, 6, 11 when empty), as a bar chart with bars
+func WithHistogram(bounds []int, bars bool) DetectOption {
+	return func(d *DetectOptions) {
+		d.dup.histogram = true
+		d.dup.histogramBounds = bounds
+		d.dup.histogramBars = bars
+	}
+}
+
+// WithKeyPrefixLen keys lines by their first n bytes only, counting lines sharing them as one
+func WithKeyPrefixLen(n int) DetectOption {
+	return func(d *DetectOptions) { d.dup.keyPrefixLen = n }
+}
+
+// WithCountColumn right-justifies the counts to width and follows them with sep rather than a
+// tab; 7 and " " match uniq -c
+func WithCountColumn(width int, sep string) DetectOption {
+	return func(d *DetectOptions) {
+		d.dup.countWidth = width
+		d.dup.countSep = sep
+	}
+}
+
+// WithCountOnly prints only the totals, keeping no locations
+func WithCountOnly(on bool) DetectOption {
+	return func(d *DetectOptions) { d.dup.countOnly = on }
+}
+
+// WithCompactLocations prints line numbers as ranges, "10-13,20"
+func WithCompactLocations(on bool) DetectOption {
+	return func(d *DetectOptions) { d.dup.compactLocations = on }
+}
+
+// WithContextLines prints n lines before and after every occurrence, like grep -C
+func WithContextLines(n int) DetectOption {
+	return func(d *DetectOptions) { d.dup.contextLines = n }
+}
+
+// WithContinuousLineNumbers numbers lines on across files, as if they were concatenated
+func WithContinuousLineNumbers(on bool) DetectOption {
+	return func(d *DetectOptions) { d.dup.continuousLineNumbers = on }
+}
+
+// WithCharset decodes the input files from enc, e.g. one from ParseCharset, rather than reading
+// them as UTF-8
+func WithCharset(enc encoding.Encoding) DetectOption {
+	return func(d *DetectOptions) { d.dup.charset = enc }
+}
+
+// WithOutput prints the report to w rather than stdout
+func WithOutput(w io.Writer) DetectOption {
+	return func(d *DetectOptions) { d.dup.out = w }
+}
+
+// WithWarnings prints warnings to w rather than stderr
+func WithWarnings(w io.Writer) DetectOption {
+	return func(d *DetectOptions) { d.dup.warn = w }
+}
+
+// WithSummary has Detect end with a one line summary of the run on w, e.g. os.Stderr for CI logs:
+// "scanned=N duplicates=M errors=K duration=D", see ex3_summary.go
+func WithSummary(w io.Writer) DetectOption {
+	return func(d *DetectOptions) { d.dup.summary = w }
+}
+
+// Detect prints the duplicate lines of files, stdin when there are none. Sorted input is a
+// single file, only the first one is read.
+func Detect(files []string, options ...DetectOption) {
+	d := newDetectOptions(options...)
+	if d.dup.summary != nil {
+		d.dup.tally = newRunTally(d.dup.progress)
+		d.dup.progress = d.dup.tally.progress
+		defer d.dup.tally.write(d.dup.summary)
+	}
+	if err := errors.Join(checkThreshold(d.dup.threshold), checkSampleRate(d.dup.sampleRate)); err != nil {
+		fmt.Fprintf(d.dup.warn, "Error: %s\n", err)
+		d.dup.tally.failed()
+		return
+	}
+	if len(files) == 0 {
+		files = []string{"stdin"}
+	}
+	if d.sorted {
+		dupDetectSorted(d.dup, files[0])
+		return
+	}
+	detectFiles(d.dup, files...)
+}
+
+// DetectReportWith is Detect returning the report rather than printing it
+func DetectReportWith(files []string, options ...DetectOption) (*DuplicateReport, error) {
+	d := newDetectOptions(options...)
+	if err := errors.Join(checkThreshold(d.dup.threshold), checkSampleRate(d.dup.sampleRate)); err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		files = []string{"stdin"}
+	}
+	if d.sorted {
+		if len(files) > 1 {
+			return nil, errors.New("sorted input is a single file, see DupDetectSortedMulti for shards")
+		}
+		return sortedReport(&d.dup, files[0])
+	}
+	return newDuplicateReport(countLines(d.dup, files...), &d.dup), nil
+}
+
+// HasDuplicates says whether files have any line reported under options, e.g. for a quiet mode
+// that only answers with an exit status. Locations aren't kept, only the count is needed.
+func HasDuplicates(files []string, options ...DetectOption) (bool, error) {
+	report, err := DetectReportWith(files, append(options, WithCountOnly(true))...)
+	if err != nil {
+		return false, err
+	}
+	return len(report.Entries) > 0, nil
+}
@@ -0,0 +1,160 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Options
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectOptions(t *testing.T) {
+	dir := t.TempDir()
+	f := writeTestFile(t, dir, "a", "Error 1\nerror 2\nERROR 3\nok\nok\nok\n")
+
+	var out bytes.Buffer
+	Detect([]string{f}, WithOutput(&out), WithThreshold(2), WithCountOnly(true))
+	if got := out.String(); got != "1 duplicated lines, 2 redundant occurrences\n" {
+		t.Errorf("threshold 2, count only: %q", got)
+	}
+
+	digits := regexp.MustCompile(`[0-9]+`)
+	report, err := DetectReportWith([]string{f}, WithCaseInsensitive(true),
+		WithPreprocess(func(s string) string { return digits.ReplaceAllString(s, "N") }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &DuplicateReport{Threshold: 1, Entries: []DuplicateEntry{
+		{Text: "Error 1", Count: 3, Locations: map[string][]int{f: {1, 2, 3}}},
+		{Text: "ok", Count: 3, Locations: map[string][]int{f: {4, 5, 6}}},
+	}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("case insensitive, preprocessed: %+v, want %+v", report, want)
+	}
+
+	report, err = DetectReportWith([]string{f}, WithHeadLimit(5), WithMinLineLength(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Entries) != 0 {
+		t.Errorf("head limit 5, min length 3: %+v, want nothing", report.Entries)
+	}
+
+	sorted := writeTestFile(t, dir, "sorted", "a\na\nb\n")
+	out.Reset()
+	Detect([]string{sorted}, WithSorted(true), WithOutput(&out))
+	if got := out.String(); got != "\n2\ta\tstart: 1, end: 2\n" {
+		t.Errorf("sorted: %q", got)
+	}
+	if _, err := DetectReportWith([]string{sorted, f}, WithSorted(true)); err == nil {
+		t.Error("expected an error for sorted input in two files")
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	dir := t.TempDir()
+	f := writeTestFile(t, dir, "a", "once\ntwice\nthrice\ntwice\nthrice\nthrice\n")
+	for _, tc := range []struct {
+		threshold int
+		want      []DuplicateEntry
+	}{
+		{0, []DuplicateEntry{
+			{Text: "thrice", Count: 3, Locations: map[string][]int{f: {3, 5, 6}}},
+			{Text: "twice", Count: 2, Locations: map[string][]int{f: {2, 4}}},
+			{Text: "once", Count: 1, Locations: map[string][]int{f: {1}}},
+		}},
+		{1, []DuplicateEntry{
+			{Text: "thrice", Count: 3, Locations: map[string][]int{f: {3, 5, 6}}},
+			{Text: "twice", Count: 2, Locations: map[string][]int{f: {2, 4}}},
+		}},
+		{2, []DuplicateEntry{
+			{Text: "thrice", Count: 3, Locations: map[string][]int{f: {3, 5, 6}}},
+		}},
+	} {
+		report, err := DetectReportWith([]string{f}, WithThreshold(tc.threshold))
+		if err != nil {
+			t.Fatalf("threshold %d: %v", tc.threshold, err)
+		}
+		if !reflect.DeepEqual(report.Entries, tc.want) {
+			t.Errorf("threshold %d: %+v, want %+v", tc.threshold, report.Entries, tc.want)
+		}
+
+		var out bytes.Buffer
+		Detect([]string{f}, WithThreshold(tc.threshold), WithOutput(&out))
+		if lines := strings.Count(out.String(), "lineNums"); lines != len(tc.want) {
+			t.Errorf("threshold %d: printed %d lines, want %d:\n%s", tc.threshold, lines, len(tc.want), out.String())
+		}
+	}
+
+	if _, err := DetectReportWith([]string{f}, WithThreshold(-1)); !errors.Is(err, ErrNegativeThreshold) {
+		t.Errorf("threshold -1: got %v, want ErrNegativeThreshold", err)
+	}
+	var out, warn bytes.Buffer
+	Detect([]string{f}, WithThreshold(-1), WithOutput(&out), WithWarnings(&warn))
+	if out.Len() != 0 || !strings.Contains(warn.String(), "negative threshold -1") {
+		t.Errorf("threshold -1: printed %q, warned %q", out.String(), warn.String())
+	}
+	if err := FollowFile(t.Context(), -1, f, time.Second); !errors.Is(err, ErrNegativeThreshold) {
+		t.Errorf("following with threshold -1: got %v, want ErrNegativeThreshold", err)
+	}
+}
+
+func TestHasDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	f := writeTestFile(t, dir, "a", "x\ny\nx\n")
+	for _, tc := range []struct {
+		threshold int
+		want      bool
+	}{
+		{0, true},
+		{1, true},
+		{2, false},
+	} {
+		found, err := HasDuplicates([]string{f}, WithThreshold(tc.threshold))
+		if err != nil || found != tc.want {
+			t.Errorf("threshold %d: %v, %v; want %v", tc.threshold, found, err, tc.want)
+		}
+	}
+	if _, err := HasDuplicates([]string{f}, WithThreshold(-1)); err == nil {
+		t.Error("expected an error for threshold -1")
+	}
+}
+
+// Options that only set a field of dupOptions, their effect being tested with the feature
+func TestOptionSetters(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		option DetectOption
+		set    func(dupOptions) bool
+	}{
+		{"WithGroupByFile", WithGroupByFile(true), func(o dupOptions) bool { return o.groupByFile }},
+		{"WithPerFile", WithPerFile(true), func(o dupOptions) bool { return o.perFile }},
+		{"WithCollisionCheck", WithCollisionCheck(true), func(o dupOptions) bool { return o.checkCollisions }},
+		{"WithMaxKeyBytes", WithMaxKeyBytes(1 << 20), func(o dupOptions) bool { return o.maxKeyBytes == 1<<20 }},
+		{"WithMaxDisplayWidth", WithMaxDisplayWidth(80), func(o dupOptions) bool { return o.maxDisplayWidth == 80 }},
+		{"WithOffsets", WithOffsets(true), func(o dupOptions) bool { return o.withOffsets }},
+		{"WithMinPerFileCount", WithMinPerFileCount(3), func(o dupOptions) bool { return o.minPerFileCount == 3 }},
+		{"WithLocationsCap", WithLocationsCap(100), func(o dupOptions) bool { return o.locationsCap == 100 }},
+		{"WithOrder", WithOrder(OrderByFirstSeen), func(o dupOptions) bool { return o.order == OrderByFirstSeen }},
+		{"WithCasingBreakdown", WithCasingBreakdown(true), func(o dupOptions) bool { return o.casingBreakdown }},
+		{"WithHashBinary", WithHashBinary(true), func(o dupOptions) bool { return o.binaryInput == hashBinary }},
+		{"WithHistogram", WithHistogram([]int{2, 10}, true), func(o dupOptions) bool {
+			return o.histogram && o.histogramBars && reflect.DeepEqual(o.histogramBounds, []int{2, 10})
+		}},
+		{"WithKeyPrefixLen", WithKeyPrefixLen(64), func(o dupOptions) bool { return o.keyPrefixLen == 64 }},
+		{"WithCountColumn", WithCountColumn(uniqCountWidth, " "), func(o dupOptions) bool {
+			return o.countWidth == uniqCountWidth && o.countSep == " "
+		}},
+	} {
+		if d := newDetectOptions(tc.option); !tc.set(d.dup) {
+			t.Errorf("%s didn't set its option: %+v", tc.name, d.dup)
+		}
+	}
+}
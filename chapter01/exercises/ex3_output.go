@@ -0,0 +1,202 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Writing reports
+
+	A DuplicateReport renders as text (the same layout DupDetectFiles prints), JSON, CSV, NDJSON
+	or a Graphviz graph of the files, and in any format registered with RegisterRenderer.
+	WriteReport puts it in a file atomically: the report goes to a temp file next to the target,
+	which is renamed over it only once complete, so a crash or an error mid-write never leaves a
+	partial report behind.
+**/
+
+package exercises
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type OutputFormat int
+
+const (
+	FormatText OutputFormat = iota
+	FormatJSON
+	FormatCSV
+	FormatNDJSON // a JSON object per location, see WriteNDJSON
+	FormatDOT    // files sharing duplicates, see ex3_dot.go
+	// JSON indented for reading; FormatJSON stays compact, for piping
+	FormatPrettyJSON
+)
+
+func (f OutputFormat) String() string {
+	if rf, ok := lookupFormat(f); ok {
+		return rf.name
+	}
+	return fmt.Sprintf("OutputFormat(%d)", int(f))
+}
+
+// ParseOutputFormat finds a format by name, built in or registered
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	formats.mu.RLock()
+	defer formats.mu.RUnlock()
+	var names []string
+	for i, rf := range formats.list {
+		if strings.EqualFold(s, rf.name) {
+			return OutputFormat(i), nil
+		}
+		names = append(names, rf.name)
+	}
+	return 0, fmt.Errorf("unknown output format %q, want one of %s", s, strings.Join(names, ", "))
+}
+
+// Extension is the file name extension of the format
+func (f OutputFormat) Extension() string {
+	if f == FormatPrettyJSON {
+		return FormatJSON.String()
+	}
+	return f.String()
+}
+
+// Write renders the report to w with the renderer of format
+func (r *DuplicateReport) Write(w io.Writer, format OutputFormat) error {
+	rf, ok := lookupFormat(format)
+	if !ok {
+		return fmt.Errorf("unknown output format %v", format)
+	}
+	return rf.renderer.Render(w, r)
+}
+
+func writeJSON(w io.Writer, r *DuplicateReport) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+func writePrettyJSON(w io.Writer, r *DuplicateReport) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func writeNDJSON(w io.Writer, r *DuplicateReport) error {
+	enc := json.NewEncoder(w)
+	for _, entry := range r.Entries {
+		if err := entry.writeNDJSON(enc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *DuplicateReport) writeText(w io.Writer) error {
+	for _, entry := range r.Entries {
+		if entry.File != "" {
+			if _, err := fmt.Fprintf(w, "%d\t%s\tstart: %d, end: %d\n", entry.Count, entry.Text, entry.Start, entry.End); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%d\t%s\n", entry.Count, entry.Text); err != nil {
+			return err
+		}
+		if entry.BaselineCount > 0 {
+			if _, err := fmt.Fprintf(w, "\t(was %d)\n", entry.BaselineCount); err != nil {
+				return err
+			}
+		}
+		for _, fileName := range entry.fileNames() {
+			if _, err := fmt.Fprintf(w, "\tFileName: %s, lineNums: %+v\n", fileName, entry.Locations[fileName]); err != nil {
+				return err
+			}
+		}
+		if entry.Truncated {
+			if _, err := fmt.Fprintln(w, "\t(locations truncated)"); err != nil {
+				return err
+			}
+		}
+		if len(entry.Casings) > 0 {
+			if _, err := fmt.Fprintf(w, "\tCasings: %s\n", formatCasings(entry.Casings)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeCSV writes a row per entry and file, lines being space separated line numbers or a
+// start-end range for sorted input
+func (r *DuplicateReport) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"count", "text", "file", "lines"})
+	for _, entry := range r.Entries {
+		count := strconv.Itoa(entry.Count)
+		if entry.File != "" {
+			cw.Write([]string{count, entry.Text, entry.File, fmt.Sprintf("%d-%d", entry.Start, entry.End)})
+			continue
+		}
+		for _, fileName := range entry.fileNames() {
+			var lines []string
+			for _, n := range entry.Locations[fileName] {
+				lines = append(lines, strconv.Itoa(n))
+			}
+			cw.Write([]string{count, entry.Text, fileName, strings.Join(lines, " ")})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (entry DuplicateEntry) fileNames() []string {
+	names := make([]string, 0, len(entry.Locations))
+	for fileName := range entry.Locations {
+		names = append(names, fileName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteReport writes the report to path, replacing any existing file only once the new one is complete
+func WriteReport(path string, r *DuplicateReport, format OutputFormat) error {
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return r.Write(w, format)
+	})
+}
+
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	return writeFileAtomicMode(path, 0o644, write)
+}
+
+func writeFileAtomicMode(path string, mode os.FileMode, write func(io.Writer) error) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
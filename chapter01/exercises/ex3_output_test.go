@@ -0,0 +1,132 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Writing reports
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testReport() *DuplicateReport {
+	return &DuplicateReport{Threshold: 1, Entries: []DuplicateEntry{
+		{Text: "x", Count: 3, Locations: map[string][]int{"b": {4}, "a": {1, 3}}},
+		{Text: "has, comma", Count: 2, File: "sorted", Start: 5, End: 6},
+	}}
+}
+
+func TestWriteReport(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		format OutputFormat
+		want   string
+	}{
+		{FormatText, "3\tx\n\tFileName: a, lineNums: [1 3]\n\tFileName: b, lineNums: [4]\n" +
+			"2\thas, comma\tstart: 5, end: 6\n"},
+		{FormatCSV, "count,text,file,lines\n3,x,a,1 3\n3,x,b,4\n2,\"has, comma\",sorted,5-6\n"},
+	} {
+		path := filepath.Join(dir, "report."+tc.format.String())
+		if err := WriteReport(path, testReport(), tc.format); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("%v report:\n%q\nwant:\n%q", tc.format, got, tc.want)
+		}
+	}
+
+	path := filepath.Join(dir, "report.json")
+	if err := WriteReport(path, testReport(), FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded DuplicateReport
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, testReport()) {
+		t.Errorf("JSON round trip = %+v, want %+v", decoded, testReport())
+	}
+}
+
+func TestPrettyJSON(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		indented bool
+	}{
+		{"json", false},
+		{"json-pretty", true},
+	} {
+		format, err := ParseOutputFormat(tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if err := testReport().Write(&out, format); err != nil {
+			t.Fatal(err)
+		}
+		var decoded DuplicateReport
+		if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+			t.Fatalf("%s: %v in %q", tc.name, err, out.String())
+		}
+		if !reflect.DeepEqual(&decoded, testReport()) {
+			t.Errorf("%s round trip = %+v, want %+v", tc.name, decoded, testReport())
+		}
+		if indented := strings.Contains(out.String(), "\n  \""); indented != tc.indented {
+			t.Errorf("%s: indented %v, want %v:\n%s", tc.name, indented, tc.indented, out.String())
+		}
+		if ext := format.Extension(); ext != "json" {
+			t.Errorf("%s: extension %q, want json", tc.name, ext)
+		}
+	}
+}
+
+func TestWriteReportFailureLeavesNoPartialFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.txt")
+	if err := os.WriteFile(path, []byte("previous report\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	failure := errors.New("disk on fire")
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		io.WriteString(w, "half a rep")
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, the temp file was left behind", len(entries))
+	}
+	if got, _ := os.ReadFile(path); string(got) != "previous report\n" {
+		t.Errorf("existing report was changed to %q", got)
+	}
+
+	if err := WriteReport(filepath.Join(dir, "new.txt"), testReport(), OutputFormat(42)); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.txt")); !os.IsNotExist(err) {
+		t.Errorf("failed write created the report: %v", err)
+	}
+}
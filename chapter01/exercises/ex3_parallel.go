@@ -0,0 +1,27 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Parallelism knobs
+
+	A scan reads files on a bounded number of goroutines and keys their lines either right there
+	or on a separate pool of hash workers. The two limits are independent: fewer readers for I/O
+	bound scans (network filesystems, spinning disks), more hashers for CPU bound ones (long lines,
+	normalization).
+**/
+
+package exercises
+
+// Parallelism bounds the goroutines of a scan, zero values pick the defaults
+type Parallelism struct {
+	Files int // files read at once, runtime.NumCPU() when 0
+	Hash  int // separate hash workers, 0 to key lines in the readers (keeps file order)
+}
+
+func (p Parallelism) apply(opts *dupOptions) {
+	opts.parallelFiles = p.Files
+	opts.parallelHash = p.Hash
+}
+
+// DupDetectFilesParallel is DupDetectFiles over unsorted files with the given parallelism
+func DupDetectFilesParallel(threshold int, p Parallelism, files ...string) {
+	Detect(files, WithThreshold(threshold), WithParallelism(p))
+}
@@ -0,0 +1,140 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Parallelism knobs
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrency tracks how many callers are inside at once, and the most there ever were
+type concurrency struct {
+	now, peak atomic.Int64
+}
+
+func (c *concurrency) enter() {
+	n := c.now.Add(1)
+	for {
+		peak := c.peak.Load()
+		if n <= peak || c.peak.CompareAndSwap(peak, n) {
+			return
+		}
+	}
+}
+
+func (c *concurrency) leave() { c.now.Add(-1) }
+
+// countingReader counts as busy until closed, and reads slowly enough for readers to overlap
+type countingReader struct {
+	io.Reader
+	c *concurrency
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+	return r.Reader.Read(p[:min(len(p), 256)])
+}
+
+func (r countingReader) Close() error {
+	r.c.leave()
+	return nil
+}
+
+type countingHasher struct {
+	c *concurrency
+}
+
+func (h countingHasher) Hash(s string) string {
+	h.c.enter()
+	defer h.c.leave()
+	time.Sleep(100 * time.Microsecond)
+	return hashString(s)
+}
+
+func TestParallelismLimits(t *testing.T) {
+	long := strings.Repeat("a long line that always gets hashed ", 2)
+	var content strings.Builder
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&content, "%s%d\n", long, i%10)
+	}
+
+	for _, p := range []Parallelism{{Files: 1, Hash: 1}, {Files: 3, Hash: 2}, {Files: 2, Hash: 5}} {
+		var readers, hashers concurrency
+		opts := defaultDupOptions(1)
+		p.apply(&opts)
+		opts.opener = func(name string) (io.ReadCloser, error) {
+			readers.enter()
+			return countingReader{strings.NewReader(content.String()), &readers}, nil
+		}
+		opts.hasher = countingHasher{&hashers}
+
+		var files []string
+		for i := 0; i < 8; i++ {
+			files = append(files, fmt.Sprintf("file%d", i))
+		}
+		counts := countLines(opts, files...)
+
+		if got := counts[hashString(long+"3")].count; got != 8*2 {
+			t.Errorf("%+v: count = %d, want %d", p, got, 8*2)
+		}
+		if peak := readers.peak.Load(); peak > int64(p.Files) {
+			t.Errorf("%+v: %d files read at once", p, peak)
+		}
+		if peak := hashers.peak.Load(); peak > int64(p.Hash) {
+			t.Errorf("%+v: %d lines hashed at once", p, peak)
+		}
+		if readers.now.Load() != 0 {
+			t.Errorf("%+v: %d files left open", p, readers.now.Load())
+		}
+	}
+}
+
+func TestParallelLocationsSorted(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := 0; i < 6; i++ {
+		content := strings.Repeat("same\nother\n", 300)
+		files = append(files, writeTestFile(t, dir, fmt.Sprintf("%d.txt", i), content))
+	}
+
+	opts := defaultDupOptions(1)
+	Parallelism{Files: 6, Hash: 4}.apply(&opts)
+	opts.withOffsets = true
+	for key, lineDatum := range countLines(opts, files...) {
+		for fileName, lineNums := range lineDatum.locations {
+			if !slices.IsSorted(lineNums) {
+				t.Fatalf("%q in %s: line numbers out of order %v", key, fileName, lineNums)
+			}
+			for i, n := range lineNums {
+				// "same" starts the odd lines, "other" the even ones, 11 bytes a pair
+				want := int64((n-1)/2*11 + (n-1)%2*5)
+				if got := lineDatum.offsets[fileName][i]; got != want {
+					t.Fatalf("%q in %s: line %d at offset %d, want %d", key, fileName, n, got, want)
+				}
+			}
+		}
+	}
+
+	var first, second bytes.Buffer
+	for _, out := range []*bytes.Buffer{&first, &second} {
+		Detect(files, WithParallelism(Parallelism{Files: 6, Hash: 4}), WithOutput(out))
+	}
+	if first.String() != second.String() {
+		t.Errorf("same input printed differently:\n%s\nthen\n%s", first.String(), second.String())
+	}
+}
+
+func TestWorkerCountDefault(t *testing.T) {
+	if workerCount(3) != 3 || workerCount(0) < 1 {
+		t.Errorf("workerCount(3) = %d, workerCount(0) = %d", workerCount(3), workerCount(0))
+	}
+}
@@ -0,0 +1,135 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Progress of a long running scan
+
+	The readers report progress through dupOptions.progress; ScanStatus folds those reports into
+	atomics, so the /progress handler can read them while the scan is running. Percent complete is an
+	estimate: bytes read against the sizes of the input files, which undercounts for stdin and
+	overshoots for compressed files (it is capped at 99 until the scan is actually done).
+**/
+
+package exercises
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type progressEvent struct {
+	fileName string
+	lines    int   // read since the previous event
+	bytes    int64 // read since the previous event
+	fileDone bool
+}
+
+// Reporting every line would make the readers contend on the status atomics
+const progressEvery = 1024
+
+type progressTracker struct {
+	fileName string
+	progress func(progressEvent)
+	lines    int
+	bytes    int64
+}
+
+func (t *progressTracker) line(length int) {
+	if t.progress == nil {
+		return
+	}
+	t.lines++
+	t.bytes += int64(length) + 1 // the newline
+	if t.lines == progressEvery {
+		t.flush()
+	}
+}
+
+func (t *progressTracker) flush() {
+	if t.progress == nil || t.lines == 0 {
+		return
+	}
+	t.progress(progressEvent{fileName: t.fileName, lines: t.lines, bytes: t.bytes})
+	t.lines, t.bytes = 0, 0
+}
+
+// ScanStatus is the live progress of a scan started with StartScan
+type ScanStatus struct {
+	totalFiles   int64
+	totalBytes   int64
+	filesDone    atomic.Int64
+	linesScanned atomic.Int64
+	bytesScanned atomic.Int64
+	done         atomic.Bool
+}
+
+func (s *ScanStatus) update(ev progressEvent) {
+	s.linesScanned.Add(int64(ev.lines))
+	s.bytesScanned.Add(ev.bytes)
+	if ev.fileDone {
+		s.filesDone.Add(1)
+	}
+}
+
+type scanProgress struct {
+	FilesTotal   int64   `json:"files_total"`
+	FilesDone    int64   `json:"files_done"`
+	LinesScanned int64   `json:"lines_scanned"`
+	BytesScanned int64   `json:"bytes_scanned"`
+	Percent      float64 `json:"percent"`
+	Done         bool    `json:"done"`
+}
+
+func (s *ScanStatus) snapshot() scanProgress {
+	p := scanProgress{
+		FilesTotal:   s.totalFiles,
+		FilesDone:    s.filesDone.Load(),
+		LinesScanned: s.linesScanned.Load(),
+		BytesScanned: s.bytesScanned.Load(),
+		Done:         s.done.Load(),
+	}
+	switch {
+	case p.Done:
+		p.Percent = 100
+	case s.totalBytes > 0:
+		p.Percent = min(99, 100*float64(p.BytesScanned)/float64(s.totalBytes))
+	}
+	return p
+}
+
+func (s *ScanStatus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.snapshot())
+}
+
+// ProgressRouter serves the status of a running scan at GET /progress
+func ProgressRouter(status *ScanStatus) http.Handler {
+	r := chi.NewRouter()
+	r.Method(http.MethodGet, "/progress", status)
+	return r
+}
+
+// StartScan runs the duplicate finder over files in the background and returns its live status
+func StartScan(threshold int, files ...string) (*ScanStatus, error) {
+	if err := checkThreshold(threshold); err != nil {
+		return nil, err
+	}
+	return startScan(defaultDupOptions(threshold), files...), nil
+}
+
+func startScan(opts dupOptions, files ...string) *ScanStatus {
+	status := &ScanStatus{totalFiles: int64(len(files))}
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil {
+			status.totalBytes += info.Size()
+		}
+	}
+	opts.progress = status.update
+	go func() {
+		detectFiles(opts, files...)
+		status.done.Store(true)
+	}()
+	return status
+}
@@ -0,0 +1,68 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Progress of a long running scan
+**/
+
+package exercises
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanProgressEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := 0; i < 3; i++ {
+		var sb strings.Builder
+		for j := 0; j < 3000; j++ {
+			fmt.Fprintf(&sb, "file %d line %d\n", i, j%100)
+		}
+		files = append(files, writeTestFile(t, dir, fmt.Sprintf("f%d", i), sb.String()))
+	}
+
+	opts := defaultDupOptions(1)
+	opts.out = io.Discard
+	status := startScan(opts, files...)
+	ts := httptest.NewServer(ProgressRouter(status))
+	defer ts.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	var p scanProgress
+	for !p.Done {
+		if time.Now().After(deadline) {
+			t.Fatalf("scan not done in time, last progress %+v", p)
+		}
+		res, err := http.Get(ts.URL + "/progress")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = json.NewDecoder(res.Body).Decode(&p)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.Done && p.Percent >= 100 {
+			t.Errorf("percent %v before the scan is done", p.Percent)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	want := scanProgress{FilesTotal: 3, FilesDone: 3, LinesScanned: 9000, BytesScanned: status.totalBytes, Percent: 100, Done: true}
+	if p != want {
+		t.Errorf("final progress %+v, want %+v", p, want)
+	}
+}
+
+func TestStartScanNegativeThreshold(t *testing.T) {
+	if _, err := StartScan(-1, "a"); !errors.Is(err, ErrNegativeThreshold) {
+		t.Errorf("err = %v, want ErrNegativeThreshold", err)
+	}
+}
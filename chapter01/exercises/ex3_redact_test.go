@@ -0,0 +1,64 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Redaction
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	dir := t.TempDir()
+	secret := "login failed for alice@example.com from 10.1.2.3"
+	long := "token=" + strings.Repeat("s3cr3t", 10)
+	f := writeTestFile(t, dir, "a", secret+"\n"+long+"\nLogin failed for ALICE@example.com from 10.1.2.3\n"+long+"\n")
+
+	var out bytes.Buffer
+	Detect([]string{f}, WithRedact(HashLine), WithCaseInsensitive(true), WithContextLines(1), WithOutput(&out))
+	for _, leak := range []string{"alice", "ALICE", "example", "10.1.2.3", "token", "s3cr3t"} {
+		if strings.Contains(strings.ToLower(out.String()), strings.ToLower(leak)) {
+			t.Errorf("output leaks %q:\n%s", leak, out.String())
+		}
+	}
+	if !strings.Contains(out.String(), HashLine(long)) {
+		t.Errorf("output has no hash of the long line:\n%s", out.String())
+	}
+
+	report, err := DetectReportWith([]string{f}, WithRedact(MaskLine))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DuplicateEntry{
+		{Text: MaskLine(long), Count: 2, Locations: map[string][]int{f: {2, 4}}},
+	}
+	if !reflect.DeepEqual(report.Entries, want) {
+		t.Errorf("masked report: %+v, want %+v", report.Entries, want)
+	}
+
+	sorted := writeTestFile(t, dir, "sorted", "pin 1234\npin 1234\n")
+	out.Reset()
+	Detect([]string{sorted}, WithSorted(true), WithRedact(MaskLine), WithOutput(&out))
+	if got := out.String(); got != "\n2\t*** ****\tstart: 1, end: 2\n" {
+		t.Errorf("sorted, masked: %q", got)
+	}
+}
+
+func TestRedactFunctions(t *testing.T) {
+	if got := MaskLine("user Bob, id 42: ok"); got != "**** ***, ** **: **" {
+		t.Errorf("MaskLine = %q", got)
+	}
+	if a, b := HashLine("x"), HashLine("x"); a != b || !strings.HasPrefix(a, "sha256:") || len(a) != 7+16 {
+		t.Errorf("HashLine = %q, %q", a, b)
+	}
+	if HashLine("x") == HashLine("y") {
+		t.Error("different lines, same hash")
+	}
+	if _, err := ParseRedact("blur"); err == nil {
+		t.Error("expected an error for an unknown redaction")
+	}
+}
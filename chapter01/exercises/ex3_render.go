@@ -0,0 +1,71 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Renderers
+
+	Every OutputFormat is backed by a Renderer, looked up in a registry that starts out with the
+	built-in formats. A caller needing a format of its own, say the event layout of their SIEM,
+	registers a Renderer under a name and gets an OutputFormat back, which then works everywhere
+	a built-in one does: Write, WriteReport, WriteShards, ParseOutputFormat and so -format.
+	Registering is meant for init time, but is safe at any time.
+**/
+
+package exercises
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Renderer writes a report in some format
+type Renderer interface {
+	Render(w io.Writer, r *DuplicateReport) error
+}
+
+// RendererFunc is a function used as a Renderer
+type RendererFunc func(w io.Writer, r *DuplicateReport) error
+
+func (f RendererFunc) Render(w io.Writer, r *DuplicateReport) error {
+	return f(w, r)
+}
+
+type registeredFormat struct {
+	name     string
+	renderer Renderer
+}
+
+// formats holds the renderer of every OutputFormat, at its index
+var formats = struct {
+	mu   sync.RWMutex
+	list []registeredFormat
+}{list: []registeredFormat{
+	FormatText:       {"text", RendererFunc(func(w io.Writer, r *DuplicateReport) error { return r.writeText(w) })},
+	FormatJSON:       {"json", RendererFunc(writeJSON)},
+	FormatCSV:        {"csv", RendererFunc(func(w io.Writer, r *DuplicateReport) error { return r.writeCSV(w) })},
+	FormatNDJSON:     {"ndjson", RendererFunc(writeNDJSON)},
+	FormatDOT:        {"dot", RendererFunc(func(w io.Writer, r *DuplicateReport) error { return r.writeDOT(w) })},
+	FormatPrettyJSON: {"json-pretty", RendererFunc(writePrettyJSON)},
+}}
+
+func lookupFormat(f OutputFormat) (registeredFormat, bool) {
+	formats.mu.RLock()
+	defer formats.mu.RUnlock()
+	if f < 0 || int(f) >= len(formats.list) {
+		return registeredFormat{}, false
+	}
+	return formats.list[f], true
+}
+
+// RegisterRenderer adds a format rendered by renderer, under a name not taken yet
+func RegisterRenderer(name string, renderer Renderer) (OutputFormat, error) {
+	formats.mu.Lock()
+	defer formats.mu.Unlock()
+	for _, rf := range formats.list {
+		if strings.EqualFold(name, rf.name) {
+			return 0, fmt.Errorf("output format %q is already registered", name)
+		}
+	}
+	formats.list = append(formats.list, registeredFormat{name, renderer})
+	return OutputFormat(len(formats.list) - 1), nil
+}
@@ -0,0 +1,51 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Renderers
+**/
+
+package exercises
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegisterRenderer(t *testing.T) {
+	calls := 0
+	siem, err := RegisterRenderer("siem-test", RendererFunc(func(w io.Writer, r *DuplicateReport) error {
+		calls++
+		for _, entry := range r.Entries {
+			fmt.Fprintf(w, "CEF:0|dups|%s|%d\n", entry.Text, entry.Count)
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if siem.String() != "siem-test" || siem.Extension() != "siem-test" {
+		t.Errorf("registered format is %q, extension %q", siem, siem.Extension())
+	}
+
+	format, err := ParseOutputFormat("SIEM-test")
+	if err != nil || format != siem {
+		t.Fatalf("parsing the registered name: %v, %v", format, err)
+	}
+	path := filepath.Join(t.TempDir(), "report.cef")
+	if err := WriteReport(path, testReport(), format); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(path)
+	if want := "CEF:0|dups|x|3\nCEF:0|dups|has, comma|2\n"; calls != 1 || string(got) != want {
+		t.Errorf("%d calls, wrote %q; want 1, %q", calls, got, want)
+	}
+
+	if _, err := RegisterRenderer("JSON", RendererFunc(writeJSON)); err == nil {
+		t.Error("expected an error for a name taken by a built-in format")
+	}
+	if err := testReport().Write(io.Discard, OutputFormat(-1)); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
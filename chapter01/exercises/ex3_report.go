@@ -0,0 +1,85 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Structured reports
+
+	The DupDetect variants print as they go. DetectReport returns the same findings as a
+	DuplicateReport instead, whichever detector produced them, so a caller doesn't need to know
+	whether the input was sorted. Unsorted entries list every occurrence per file; sorted entries
+	carry the run as a start and end line number.
+**/
+
+package exercises
+
+import (
+	"sort"
+)
+
+type DuplicateReport struct {
+	Threshold int              `json:"threshold"`
+	Entries   []DuplicateEntry `json:"entries"` // most frequent first, then by text, unless asked otherwise
+	// Scanning stopped at the byte budget, the counts are of part of the input
+	BudgetReached bool `json:"budget_reached,omitempty"`
+	// Counts are estimates from a sample of this fraction of the lines; 0 when every line counted
+	SampleRate float64 `json:"sample_rate,omitempty"`
+}
+
+type DuplicateEntry struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+	// Unsorted input: line numbers of every occurrence, per file
+	Locations map[string][]int   `json:"locations,omitempty"`
+	Truncated bool               `json:"locations_truncated,omitempty"` // Locations is capped, Count is not
+	Casings   map[string]int     `json:"casings,omitempty"`             // case-insensitive only, per original casing
+	Offsets   map[string][]int64 `json:"offsets,omitempty"`             // byte offsets matching Locations, when recorded
+	// Sorted input: the run of the line, from Start to End inclusive
+	File  string `json:"file,omitempty"`
+	Start int    `json:"start,omitempty"`
+	End   int    `json:"end,omitempty"`
+	// Compared with a baseline (see Since): the count there, 0 for a new duplicate
+	BaselineCount int `json:"baseline_count,omitempty"`
+}
+
+// DetectReport is DupDetectFiles returning a report rather than printing one. No files means stdin.
+func DetectReport(threshold int, sorted bool, files ...string) (*DuplicateReport, error) {
+	options := []DetectOption{WithThreshold(threshold)}
+	if sorted {
+		options = append(options, WithSorted(true))
+	}
+	return DetectReportWith(files, options...)
+}
+
+func newDuplicateReport(counts map[string]lineData, opts *dupOptions) *DuplicateReport {
+	var lines []string
+	for line, lineDatum := range counts {
+		if opts.reported(lineDatum) {
+			lines = append(lines, line)
+		}
+	}
+	sortLines(lines, counts, opts.order)
+
+	report := &DuplicateReport{Threshold: opts.threshold, BudgetReached: opts.byteBudget.exhausted()}
+	if opts.sampling() {
+		report.SampleRate = opts.sampleRate
+	}
+	for _, line := range lines {
+		lineDatum := counts[line]
+		report.Entries = append(report.Entries, DuplicateEntry{
+			Text:      lineDatum.displayText(line),
+			Count:     lineDatum.count,
+			Locations: lineDatum.locations,
+			Truncated: lineDatum.truncated,
+			Casings:   lineDatum.casings,
+			Offsets:   lineDatum.offsets,
+		})
+	}
+	return report
+}
+
+func (r *DuplicateReport) sort() {
+	sort.Slice(r.Entries, func(i, j int) bool {
+		if r.Entries[i].Count != r.Entries[j].Count {
+			return r.Entries[i].Count > r.Entries[j].Count
+		}
+		return r.Entries[i].Text < r.Entries[j].Text
+	})
+}
@@ -0,0 +1,50 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Structured reports
+**/
+
+package exercises
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectReportSorted(t *testing.T) {
+	f := writeTestFile(t, t.TempDir(), "sorted", "a\na\nb\nc\nc\nc\nd\nd\n")
+
+	report, err := DetectReport(1, true, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DuplicateEntry{
+		{Text: "c", Count: 3, File: f, Start: 4, End: 6},
+		{Text: "a", Count: 2, File: f, Start: 1, End: 2},
+		{Text: "d", Count: 2, File: f, Start: 7, End: 8},
+	}
+	if !reflect.DeepEqual(report.Entries, want) {
+		t.Errorf("entries = %+v, want %+v", report.Entries, want)
+	}
+}
+
+func TestDetectReportUnsorted(t *testing.T) {
+	f := writeTestFile(t, t.TempDir(), "a", "x\ny\nx\nz\nx\ny\n")
+
+	report, err := DetectReport(1, false, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DuplicateEntry{
+		{Text: "x", Count: 3, Locations: map[string][]int{f: {1, 3, 5}}},
+		{Text: "y", Count: 2, Locations: map[string][]int{f: {2, 6}}},
+	}
+	if !reflect.DeepEqual(report.Entries, want) {
+		t.Errorf("entries = %+v, want %+v", report.Entries, want)
+	}
+}
+
+func TestDetectReportSortedMissingFile(t *testing.T) {
+	if _, err := DetectReport(1, true, "does-not-exist"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,54 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Retrying transient open errors
+
+	On NFS and FUSE mounts opening a file can fail for a moment (EAGAIN, ETIMEDOUT) and work when
+	tried again. collectLines retries those with exponential backoff, a bounded number of times;
+	anything else, a missing file or a permission problem, fails on the first try.
+**/
+
+package exercises
+
+import (
+	"errors"
+	"io"
+	"syscall"
+	"time"
+)
+
+type retryPolicy struct {
+	attempts int           // tries in total, 0 or 1 means no retrying
+	backoff  time.Duration // wait before the first retry, doubled for each one after
+}
+
+var defaultRetryPolicy = retryPolicy{attempts: 3, backoff: 50 * time.Millisecond}
+
+func isTransientOpenError(err error) bool {
+	for _, errno := range []syscall.Errno{syscall.EAGAIN, syscall.ETIMEDOUT, syscall.EINTR, syscall.EBUSY} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+func openWithRetry(name string, open fileOpener, policy retryPolicy) (io.ReadCloser, error) {
+	wait := policy.backoff
+	for attempt := 1; ; attempt++ {
+		file, err := open(name)
+		if err == nil || attempt >= policy.attempts || !isTransientOpenError(err) {
+			return file, err
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// openFile opens a file with the configured opener and retry policy
+func (opts *dupOptions) openFile(name string) (io.ReadCloser, error) {
+	open := opts.opener
+	if open == nil {
+		open = osOpen
+	}
+	return openWithRetry(name, open, opts.openRetry)
+}
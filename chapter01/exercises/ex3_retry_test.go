@@ -0,0 +1,71 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Retrying transient open errors
+**/
+
+package exercises
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyOpener fails with err the first failures times it's called, then serves content
+type flakyOpener struct {
+	failures int
+	err      error
+	content  string
+	calls    int
+}
+
+func (o *flakyOpener) open(name string) (io.ReadCloser, error) {
+	o.calls++
+	if o.calls <= o.failures {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: o.err}
+	}
+	return io.NopCloser(strings.NewReader(o.content)), nil
+}
+
+func TestOpenRetryTransient(t *testing.T) {
+	opener := &flakyOpener{failures: 2, err: syscall.EAGAIN, content: "again\nagain\n"}
+	opts := defaultDupOptions(1)
+	opts.opener = opener.open
+	opts.openRetry = retryPolicy{attempts: 3, backoff: time.Millisecond}
+
+	counts := countLines(opts, "nfs/file.log")
+	if got := counts["again"].count; got != 2 {
+		t.Errorf("count = %d, want 2 once the open succeeds", got)
+	}
+	if opener.calls != 3 {
+		t.Errorf("opened %d times, want 3", opener.calls)
+	}
+}
+
+func TestOpenRetryGivesUp(t *testing.T) {
+	opener := &flakyOpener{failures: 5, err: syscall.ETIMEDOUT}
+	_, err := openWithRetry("f", opener.open, retryPolicy{attempts: 3, backoff: time.Millisecond})
+	if !errors.Is(err, syscall.ETIMEDOUT) {
+		t.Errorf("err = %v, want ETIMEDOUT", err)
+	}
+	if opener.calls != 3 {
+		t.Errorf("opened %d times, want 3", opener.calls)
+	}
+}
+
+func TestOpenRetryPermanentFailsFast(t *testing.T) {
+	for _, errno := range []syscall.Errno{syscall.ENOENT, syscall.EACCES} {
+		opener := &flakyOpener{failures: 1, err: errno}
+		_, err := openWithRetry("f", opener.open, retryPolicy{attempts: 3, backoff: time.Hour})
+		if !errors.Is(err, errno) {
+			t.Errorf("err = %v, want %v", err, errno)
+		}
+		if opener.calls != 1 {
+			t.Errorf("%v: opened %d times, want 1", errno, opener.calls)
+		}
+	}
+}
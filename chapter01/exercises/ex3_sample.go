@@ -0,0 +1,80 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Sampling
+
+	For an estimate of the duplicates in input too big to count, sampleRate counts only that
+	fraction of the lines, and reports every count scaled by 1/sampleRate: the counts become
+	estimates, and a line repeated less than about 1/sampleRate times may not be seen at all.
+	Locations are those of the sampled occurrences only. The threshold applies to the estimates.
+
+	Whether a line is sampled is drawn from a hash of sampleSeed and the file name and line number
+	of the line, rather than from one generator shared by the readers, so the same seed samples
+	the same lines however the readers are scheduled. The hash is mixed by hand (FNV-1a, then
+	splitmix64) as it runs for every line, where a hasher or generator per line would allocate. With continuousLineNumbers that
+	is the number across files, drawn once every line is numbered, so a line left out doesn't
+	shift the ones after it. Sorted input is never sampled, its runs need every line.
+**/
+
+package exercises
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var ErrSampleRate = errors.New("sample rate out of range")
+
+// checkSampleRate refuses rates outside (0, 1]; 0 means no sampling
+func checkSampleRate(rate float64) error {
+	if rate < 0 || rate > 1 || math.IsNaN(rate) {
+		return fmt.Errorf("%w: %v, want more than 0 and at most 1", ErrSampleRate, rate)
+	}
+	return nil
+}
+
+// sampling says whether opts counts only part of the lines
+func (opts *dupOptions) sampling() bool {
+	return opts.sampleRate > 0 && opts.sampleRate < 1
+}
+
+// sampleEmit passes on to emit the lines drawn into the sample
+func sampleEmit(opts *dupOptions, emit func(scannedLine)) func(scannedLine) {
+	return func(line scannedLine) {
+		if sampleDraw(opts.sampleSeed, line.fileName, line.lineNum) < opts.sampleRate {
+			emit(line)
+		}
+	}
+}
+
+// sampleDraw is uniform in [0, 1), the same for the same seed, file and line
+func sampleDraw(seed uint64, fileName string, lineNum int) float64 {
+	h := uint64(14695981039346656037) // FNV-1a 64
+	for i := 0; i < len(fileName); i++ {
+		h ^= uint64(fileName[i])
+		h *= 1099511628211
+	}
+	x := splitmix64(splitmix64(seed^h) + uint64(lineNum))
+	return float64(x>>11) / (1 << 53)
+}
+
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// scaleCounts turns the counts of a sample into estimates of the counts of the whole input
+func scaleCounts(counts map[string]lineData, rate float64) {
+	for key, lineDatum := range counts {
+		lineDatum.count = int(math.Round(float64(lineDatum.count) / rate))
+		counts[key] = lineDatum
+	}
+}
+
+func noteSampled(opts *dupOptions) {
+	if opts.sampling() {
+		fmt.Fprintf(opts.warn, "Note: counts are estimates, from a sample of %v of the lines\n", opts.sampleRate)
+	}
+}
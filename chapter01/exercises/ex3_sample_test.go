@@ -0,0 +1,160 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Sampling
+**/
+
+package exercises
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampleRate(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := 0; i < 4; i++ {
+		var content strings.Builder
+		for j := 0; j < 250; j++ {
+			fmt.Fprintf(&content, "line %d\n", j%5)
+		}
+		files = append(files, writeTestFile(t, dir, fmt.Sprintf("%d.txt", i), content.String()))
+	}
+	exact, err := DetectReportWith(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := DetectReportWith(files, WithSampleRate(1), WithSampleSeed(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(all, exact) {
+		t.Errorf("rate 1: %+v, want the exact counts %+v", all, exact)
+	}
+
+	sample := func(seed uint64, p Parallelism) *DuplicateReport {
+		t.Helper()
+		report, err := DetectReportWith(files, WithSampleRate(0.5), WithSampleSeed(seed), WithParallelism(p))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+	half := sample(42, Parallelism{Files: 1})
+	if again := sample(42, Parallelism{Files: 4, Hash: 2}); !reflect.DeepEqual(again, half) {
+		t.Errorf("same seed, other scheduling: %+v, want %+v", again, half)
+	}
+	if other := sample(43, Parallelism{Files: 1}); reflect.DeepEqual(other, half) {
+		t.Error("another seed sampled the same lines")
+	}
+	if half.SampleRate != 0.5 {
+		t.Errorf("SampleRate %v, want 0.5", half.SampleRate)
+	}
+
+	sampled := 0
+	exactLocations := make(map[string]map[string][]int)
+	for _, entry := range exact.Entries {
+		exactLocations[entry.Text] = entry.Locations
+	}
+	for _, entry := range half.Entries {
+		n := 0
+		for fileName, lineNums := range entry.Locations {
+			n += len(lineNums)
+			for _, lineNum := range lineNums {
+				if !slices.Contains(exactLocations[entry.Text][fileName], lineNum) {
+					t.Errorf("%q sampled at %s:%d, where it isn't", entry.Text, fileName, lineNum)
+				}
+			}
+		}
+		if entry.Count != 2*n {
+			t.Errorf("%q: estimate %d from %d sampled, want %d", entry.Text, entry.Count, n, 2*n)
+		}
+		sampled += n
+	}
+	if sampled < 400 || sampled > 600 {
+		t.Errorf("%d of 1000 lines sampled at rate 0.5", sampled)
+	}
+
+	for _, rate := range []float64{-0.1, 1.5} {
+		if _, err := DetectReportWith(files, WithSampleRate(rate)); !errors.Is(err, ErrSampleRate) {
+			t.Errorf("rate %v: error %v, want ErrSampleRate", rate, err)
+		}
+	}
+}
+
+func TestSampleContinuousLineNumbers(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := 0; i < 2; i++ {
+		var content strings.Builder
+		for j := 0; j < 100; j++ {
+			fmt.Fprintf(&content, "line %d\n", j%7)
+		}
+		files = append(files, writeTestFile(t, dir, fmt.Sprintf("%d.txt", i), content.String()))
+	}
+	exact, err := DetectReportWith(files, WithThreshold(0), WithContinuousLineNumbers(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	half, err := DetectReportWith(files, WithThreshold(0), WithContinuousLineNumbers(true), WithSampleRate(0.5), WithSampleSeed(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Lines left out of the sample still take their number
+	exactLocations := make(map[string]map[string][]int)
+	for _, entry := range exact.Entries {
+		exactLocations[entry.Text] = entry.Locations
+	}
+	for _, entry := range half.Entries {
+		for fileName, lineNums := range entry.Locations {
+			for _, lineNum := range lineNums {
+				if !slices.Contains(exactLocations[entry.Text][fileName], lineNum) {
+					t.Errorf("%q sampled at %s:%d, where it isn't", entry.Text, fileName, lineNum)
+				}
+			}
+		}
+	}
+}
+
+func TestSampleCheckpointedScalesOnce(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := range 20 {
+		files = append(files, writeTestFile(t, dir, fmt.Sprintf("%d.txt", i), "x\nx\nx\ny\n"))
+	}
+	options := []DetectOption{WithThreshold(0), WithSampleRate(0.3), WithSampleSeed(5)}
+	want, err := DetectReportWith(files, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := Checkpoint{Path: filepath.Join(dir, "checkpoint"), Every: time.Hour}
+	got, err := DetectCheckpointed(context.Background(), files, cp, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("checkpointed %+v, want the estimates of a single scan %+v", got, want)
+	}
+}
+
+func BenchmarkSampleEmit(b *testing.B) {
+	opts := defaultDupOptions(1)
+	opts.sampleRate, opts.sampleSeed = 0.5, 1
+	sampled := 0
+	emit := sampleEmit(&opts, func(scannedLine) { sampled++ })
+	line := scannedLine{fileName: "/var/log/app/access.log"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		line.lineNum = i
+		emit(line)
+	}
+}
@@ -0,0 +1,54 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Sharded reports
+
+	For processing a report in parallel downstream, it can be split into shardCount partitions by
+	a hash of the line text, each written to its own file. The hash is FNV-1a, which is stable
+	across runs and machines, so a line always lands in the same shard for the same shardCount.
+	Every shard keeps the report order and the threshold; shards without lines are still written,
+	so a consumer can expect all shardCount files.
+**/
+
+package exercises
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+)
+
+// Shard splits r into shardCount reports by hash of the line text
+func (r *DuplicateReport) Shard(shardCount int) []*DuplicateReport {
+	shards := make([]*DuplicateReport, shardCount)
+	for i := range shards {
+		shards[i] = &DuplicateReport{Threshold: r.Threshold, Entries: []DuplicateEntry{}}
+	}
+	for _, entry := range r.Entries {
+		shard := shards[shardOf(entry.Text, shardCount)]
+		shard.Entries = append(shard.Entries, entry)
+	}
+	return shards
+}
+
+func shardOf(text string, shardCount int) int {
+	h := fnv.New32a()
+	h.Write([]byte(text))
+	return int(h.Sum32() % uint32(shardCount))
+}
+
+// WriteShards writes the shards of r into dir as shard-000.json, shard-001.json, ... (the
+// extension following the format, see Extension) and returns their paths in shard order
+func WriteShards(dir string, r *DuplicateReport, shardCount int, format OutputFormat) ([]string, error) {
+	if shardCount < 1 {
+		return nil, fmt.Errorf("shard count %d, want at least 1", shardCount)
+	}
+	var paths []string
+	for i, shard := range r.Shard(shardCount) {
+		path := filepath.Join(dir, fmt.Sprintf("shard-%03d.%s", i, format.Extension()))
+		if err := WriteReport(path, shard, format); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
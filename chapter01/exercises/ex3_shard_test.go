@@ -0,0 +1,70 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Sharded reports
+**/
+
+package exercises
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteShards(t *testing.T) {
+	dir := t.TempDir()
+	var input strings.Builder
+	for i := range 40 {
+		fmt.Fprintf(&input, "line %d\nline %d\n", i, i)
+	}
+	f := writeTestFile(t, dir, "a", input.String())
+	report, err := DetectReport(1, false, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := t.TempDir()
+	paths, err := WriteShards(out, report, 4, FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 4 || filepath.Base(paths[3]) != "shard-003.json" {
+		t.Fatalf("paths = %v, want 4 shard files", paths)
+	}
+
+	seen := make(map[string]int)
+	var union []DuplicateEntry
+	for i, path := range paths {
+		shard, err := ReadReport(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if shard.Threshold != report.Threshold {
+			t.Errorf("shard %d threshold = %d, want %d", i, shard.Threshold, report.Threshold)
+		}
+		for _, entry := range shard.Entries {
+			seen[entry.Text]++
+			if got := shardOf(entry.Text, 4); got != i {
+				t.Errorf("%q is in shard %d, hashes to %d", entry.Text, i, got)
+			}
+		}
+		union = append(union, shard.Entries...)
+	}
+	for text, n := range seen {
+		if n != 1 {
+			t.Errorf("%q is in %d shards", text, n)
+		}
+	}
+	// Each shard is in report order, so sorting the union restores the report
+	sorted := &DuplicateReport{Threshold: report.Threshold, Entries: union}
+	sorted.sort()
+	if !reflect.DeepEqual(sorted, report) {
+		t.Errorf("union of the shards differs from the report:\n%+v\nwant\n%+v", sorted, report)
+	}
+
+	if _, err := WriteShards(out, report, 0, FormatJSON); err == nil {
+		t.Error("expected an error for 0 shards")
+	}
+}
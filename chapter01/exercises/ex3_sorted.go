@@ -0,0 +1,134 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Merging many sorted shards
+
+	DupDetectSorted handles one sorted file. The output of an external sort is usually many sorted
+	shards, so DupDetectSortedMulti does a k-way merge over them with a min-heap holding the current
+	line of each shard. Equal lines come out of the merge next to each other, so only the current run
+	is ever held in memory: constant memory in the size of the input, linear in the number of shards.
+**/
+
+package exercises
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"strings"
+)
+
+type fileRange struct {
+	fileName   string
+	start, end int
+}
+
+// sortedRun is a run of identical lines in the merged stream
+type sortedRun struct {
+	text   string
+	count  int
+	ranges []fileRange // one per shard the line occurs in, in argument order
+}
+
+type shardCursor struct {
+	index   int // position in the argument list, breaks ties between equal lines
+	name    string
+	input   *bufio.Scanner
+	text    string
+	lineNum int
+}
+
+type shardHeap []*shardCursor
+
+func (h shardHeap) Len() int { return len(h) }
+func (h shardHeap) Less(i, j int) bool {
+	if h[i].text != h[j].text {
+		return h[i].text < h[j].text
+	}
+	return h[i].index < h[j].index
+}
+func (h shardHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *shardHeap) Push(x any)   { *h = append(*h, x.(*shardCursor)) }
+func (h *shardHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+func (c *shardCursor) advance() bool {
+	if !c.input.Scan() {
+		return false
+	}
+	c.text = c.input.Text()
+	c.lineNum++
+	return true
+}
+
+// mergeSortedRuns merges the sorted files and calls emit for every run of identical lines. An error
+// from emit stops the merge and is returned.
+func mergeSortedRuns(files []string, emit func(sortedRun) error) error {
+	h := make(shardHeap, 0, len(files))
+	for i, f := range files {
+		input, closer, err := openLineSource(f)
+		if err != nil {
+			return fmt.Errorf("opening %s: %w", f, err)
+		}
+		defer closer.Close()
+		c := &shardCursor{index: i, name: f, input: input}
+		if c.advance() {
+			h = append(h, c)
+		}
+	}
+	heap.Init(&h)
+
+	var run sortedRun
+	for h.Len() > 0 {
+		c := h[0]
+		if run.count > 0 && c.text != run.text {
+			if err := emit(run); err != nil {
+				return err
+			}
+			run = sortedRun{}
+		}
+		if run.count == 0 {
+			run.text = c.text
+		}
+		run.count++
+		if n := len(run.ranges); n > 0 && run.ranges[n-1].fileName == c.name {
+			run.ranges[n-1].end = c.lineNum
+		} else {
+			run.ranges = append(run.ranges, fileRange{fileName: c.name, start: c.lineNum, end: c.lineNum})
+		}
+
+		if c.advance() {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	if run.count > 0 {
+		return emit(run)
+	}
+	return nil
+}
+
+func DupDetectSortedMulti(threshold int, files ...string) {
+	if err := checkThreshold(threshold); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	err := mergeSortedRuns(files, func(run sortedRun) error {
+		if run.count <= threshold {
+			return nil
+		}
+		var ranges []string
+		for _, r := range run.ranges {
+			ranges = append(ranges, fmt.Sprintf("%s: %d-%d", r.fileName, r.start, r.end))
+		}
+		fmt.Printf("%d\t%s\t%s\n", run.count, run.text, strings.Join(ranges, ", "))
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error in merging sorted files: %s\n", err)
+	}
+}
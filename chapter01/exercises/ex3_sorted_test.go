@@ -0,0 +1,82 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Merging many sorted shards
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeSortedRuns(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a", "apple\nbanana\nbanana\ncherry\n")
+	b := writeTestFile(t, dir, "b", "banana\ndate\ndate\n")
+	c := writeTestFile(t, dir, "c", "apple\nbanana\ndate\nelder\n")
+
+	var runs []sortedRun
+	if err := mergeSortedRuns([]string{a, b, c}, func(run sortedRun) error { runs = append(runs, run); return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []sortedRun{
+		{"apple", 2, []fileRange{{a, 1, 1}, {c, 1, 1}}},
+		{"banana", 4, []fileRange{{a, 2, 3}, {b, 1, 1}, {c, 2, 2}}},
+		{"cherry", 1, []fileRange{{a, 4, 4}}},
+		{"date", 3, []fileRange{{b, 2, 3}, {c, 3, 3}}},
+		{"elder", 1, []fileRange{{c, 4, 4}}},
+	}
+	if !reflect.DeepEqual(runs, want) {
+		t.Errorf("got  %v\nwant %v", runs, want)
+	}
+}
+
+func TestMergeSortedRunsMissingFile(t *testing.T) {
+	if err := mergeSortedRuns([]string{"does/not/exist"}, func(sortedRun) error { return nil }); err == nil {
+		t.Error("expected an error for a missing shard")
+	}
+}
+
+func TestDupDetectSortedCaseInsensitive(t *testing.T) {
+	// Sorted case-insensitively, as sort -f would
+	f := writeTestFile(t, t.TempDir(), "sorted", "Apple\napple\nAPPLE\nbanana\nBanana\ncherry\n")
+
+	var out bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.caseInsensitive = true
+	opts.out = &out
+	dupDetectSorted(opts, f)
+
+	for _, want := range []string{
+		"3\tApple\tstart: 1, end: 3\n",
+		"2\tbanana\tstart: 4, end: 5\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output %q does not contain %q", out.String(), want)
+		}
+	}
+	if strings.Contains(out.String(), "cherry") {
+		t.Errorf("cherry is not a duplicate: %q", out.String())
+	}
+
+	// Case-sensitive, the same file only has single line runs
+	out.Reset()
+	opts.caseInsensitive = false
+	dupDetectSorted(opts, f)
+	if out.String() != "\n" {
+		t.Errorf("case-sensitive output %q, want no duplicates", out.String())
+	}
+}
+
+func TestCaseInsensitiveUnsorted(t *testing.T) {
+	f := writeTestFile(t, t.TempDir(), "a", "Error\nerror\nERROR\nwarn\n")
+	opts := defaultDupOptions(1)
+	opts.caseInsensitive = true
+	if got := countLines(opts, f)["error"].count; got != 3 {
+		t.Errorf("error count = %d, want 3", got)
+	}
+}
@@ -0,0 +1,110 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Line sources
+
+	Every detector reads its input through openLineSource, so stdin handling, decompression and the
+	scanner buffer size are decided in one place.
+**/
+
+package exercises
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// bufio.Scanner gives up on lines over 64KB by default; minified JSON and stack traces in logs
+// easily go past that
+const maxLineBytes = 16 << 20
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	input := bufio.NewScanner(r)
+	input.Buffer(make([]byte, 64*1024), maxLineBytes)
+	return input
+}
+
+type fileOpener func(name string) (io.ReadCloser, error)
+
+func osOpen(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+// openSource opens name for reading, "stdin" being standard input, and decompresses .gz files.
+// Stdin has no name to go by, so it is decompressed when it starts with the gzip magic number.
+// The closer releases everything that was opened; it never closes stdin.
+func openSource(name string) (io.Reader, io.Closer, error) {
+	return openSourceWith(name, osOpen)
+}
+
+// openSourceWith is openSource opening files with open
+func openSourceWith(name string, open fileOpener) (io.Reader, io.Closer, error) {
+	if name == "stdin" {
+		hintIfTerminal(os.Stdin, os.Stderr)
+		return sniffGzip(os.Stdin)
+	}
+	file, err := open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isGzipPath(name) {
+		return file, file, nil
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return gz, closerFunc(func() error {
+		gz.Close()
+		return file.Close()
+	}), nil
+}
+
+// sniffGzip decompresses r if it starts with the gzip magic number and passes it through as is
+// otherwise. The check peeks, so no byte is lost either way. Closing doesn't close r.
+func sniffGzip(r io.Reader) (io.Reader, io.Closer, error) {
+	br := bufio.NewReader(r)
+	noop := closerFunc(func() error { return nil })
+	magic, _ := br.Peek(2)
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return br, noop, nil
+	}
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, nil, err
+	}
+	return gz, gz, nil
+}
+
+// Replaced in tests, which have no terminal to read from
+var isTerminal = term.IsTerminal
+
+// hintIfTerminal tells a user who ran the program without piping anything in that it is waiting
+// for them to type, rather than hung. It reports whether in is a terminal.
+func hintIfTerminal(in *os.File, w io.Writer) bool {
+	if !isTerminal(int(in.Fd())) {
+		return false
+	}
+	fmt.Fprintln(w, "reading from stdin; press Ctrl-D to end")
+	return true
+}
+
+func openLineSource(name string) (*bufio.Scanner, io.Closer, error) {
+	r, closer, err := openSource(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newLineScanner(r), closer, nil
+}
@@ -0,0 +1,135 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Line sources
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readAllLines(t *testing.T, name string) []string {
+	t.Helper()
+	input, closer, err := openLineSource(name)
+	if err != nil {
+		t.Fatalf("openLineSource(%s): %v", name, err)
+	}
+	defer closer.Close()
+	var lines []string
+	for input.Scan() {
+		lines = append(lines, input.Text())
+	}
+	if err := input.Err(); err != nil {
+		t.Fatalf("scanning %s: %v", name, err)
+	}
+	return lines
+}
+
+func TestOpenLineSource(t *testing.T) {
+	dir := t.TempDir()
+	want := []string{"one", "two", "one"}
+	content := strings.Join(want, "\n") + "\n"
+
+	t.Run("plain", func(t *testing.T) {
+		f := writeTestFile(t, dir, "plain.log", content)
+		if got := readAllLines(t, f); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		f := filepath.Join(dir, "compressed.log.gz")
+		if err := os.WriteFile(f, gzipBytes(t, []byte(content)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if got := readAllLines(t, f); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		stdin, err := os.Open(writeTestFile(t, dir, "stdin", content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stdin.Close()
+		defer func(orig *os.File) { os.Stdin = orig }(os.Stdin)
+		os.Stdin = stdin
+
+		if got := readAllLines(t, "stdin"); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("long lines", func(t *testing.T) {
+		long := strings.Repeat("x", 1<<20)
+		f := writeTestFile(t, dir, "long.log", long+"\n"+long+"\n")
+		if got := readAllLines(t, f); len(got) != 2 || got[0] != long {
+			t.Errorf("got %d lines, want 2 lines of %d bytes", len(got), len(long))
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if _, _, err := openLineSource(filepath.Join(dir, "nope")); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestHintIfTerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	var out bytes.Buffer
+	if hintIfTerminal(r, &out) || out.Len() > 0 {
+		t.Errorf("a pipe was taken for a terminal, printed %q", out.String())
+	}
+
+	defer func(saved func(int) bool) { isTerminal = saved }(isTerminal)
+	isTerminal = func(fd int) bool { return fd == int(r.Fd()) }
+	if !hintIfTerminal(r, &out) || !strings.Contains(out.String(), "Ctrl-D") {
+		t.Errorf("no hint for a terminal, printed %q", out.String())
+	}
+}
+
+func TestCompressedStdin(t *testing.T) {
+	content := "alpha\nbeta\nalpha\n"
+	for name, input := range map[string][]byte{
+		"gzip":  gzipBytes(t, []byte(content)),
+		"plain": []byte(content),
+		// Shorter than the magic number, which must not get lost
+		"one byte": []byte("x"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			go func() {
+				w.Write(input)
+				w.Close()
+			}()
+			defer func(saved *os.File) { os.Stdin = saved }(os.Stdin)
+			os.Stdin = r
+
+			want := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+			if name == "one byte" {
+				want = []string{"x"}
+			}
+			if got := readAllLines(t, "stdin"); !reflect.DeepEqual(got, want) {
+				t.Errorf("lines = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Streaming results
+
+	StreamDuplicates and StreamSortedDuplicates send entries on a channel as they become final,
+	for use in a pipeline. What "final" means differs:
+	- unsorted input: any later line may repeat an earlier one, so nothing is final before the
+	  whole scan is done. Entries all arrive at the end, most frequent first.
+	- sorted input: a run is final as soon as a different line follows it, so entries stream
+	  while the file is read, in the file's order.
+	Both close the entry channel when done, then the error channel, after sending at most one
+	error. Cancelling ctx stops sending; the sorted scan stops reading too, the unsorted scan
+	finishes in the background and is discarded.
+**/
+
+package exercises
+
+import (
+	"context"
+)
+
+func StreamDuplicates(ctx context.Context, threshold int, files ...string) (<-chan DuplicateEntry, <-chan error) {
+	entries := make(chan DuplicateEntry)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(entries)
+		if err := checkThreshold(threshold); err != nil {
+			errs <- err
+			return
+		}
+		if len(files) == 0 {
+			files = []string{"stdin"}
+		}
+		opts := defaultDupOptions(threshold)
+		report := newDuplicateReport(countLines(opts, files...), &opts)
+		for _, entry := range report.Entries {
+			if err := sendEntry(ctx, entries, entry); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	return entries, errs
+}
+
+func StreamSortedDuplicates(ctx context.Context, threshold int, fileName string) (<-chan DuplicateEntry, <-chan error) {
+	entries := make(chan DuplicateEntry)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(entries)
+		if err := checkThreshold(threshold); err != nil {
+			errs <- err
+			return
+		}
+		err := mergeSortedRuns([]string{fileName}, func(run sortedRun) error {
+			if run.count <= threshold {
+				return ctx.Err()
+			}
+			return sendEntry(ctx, entries, DuplicateEntry{
+				Text:  run.text,
+				Count: run.count,
+				File:  fileName,
+				Start: run.ranges[0].start,
+				End:   run.ranges[0].end,
+			})
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return entries, errs
+}
+
+func sendEntry(ctx context.Context, entries chan<- DuplicateEntry, entry DuplicateEntry) error {
+	select {
+	case entries <- entry:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
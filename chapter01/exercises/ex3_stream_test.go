@@ -0,0 +1,101 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Streaming results
+**/
+
+package exercises
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func collectEntries(t *testing.T, entries <-chan DuplicateEntry, errs <-chan error) []DuplicateEntry {
+	t.Helper()
+	var got []DuplicateEntry
+	for entry := range entries {
+		got = append(got, entry)
+	}
+	// errs is closed after entries, so this doesn't block
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestStreamDuplicates(t *testing.T) {
+	f := writeTestFile(t, t.TempDir(), "a", "x\ny\nx\nz\nx\ny\n")
+
+	entries, errs := StreamDuplicates(context.Background(), 1, f)
+	got := collectEntries(t, entries, errs)
+	want := []DuplicateEntry{
+		{Text: "x", Count: 3, Locations: map[string][]int{f: {1, 3, 5}}},
+		{Text: "y", Count: 2, Locations: map[string][]int{f: {2, 6}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %+v, want %+v", got, want)
+	}
+}
+
+func TestStreamSortedDuplicates(t *testing.T) {
+	f := writeTestFile(t, t.TempDir(), "sorted", "a\na\nb\nc\nc\nc\n")
+
+	entries, errs := StreamSortedDuplicates(context.Background(), 1, f)
+	got := collectEntries(t, entries, errs)
+	// In file order, not by count
+	want := []DuplicateEntry{
+		{Text: "a", Count: 2, File: f, Start: 1, End: 2},
+		{Text: "c", Count: 3, File: f, Start: 4, End: 6},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %+v, want %+v", got, want)
+	}
+}
+
+func TestStreamSortedDuplicatesCancel(t *testing.T) {
+	f := writeTestFile(t, t.TempDir(), "sorted", "a\na\nb\nb\nc\nc\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	entries, errs := StreamSortedDuplicates(ctx, 1, f)
+	if entry := <-entries; entry.Text != "a" {
+		t.Errorf("first entry = %+v, want a", entry)
+	}
+	cancel()
+	// Not receiving, so the pending send can only give up
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if entry, ok := <-entries; ok {
+		t.Errorf("unexpected entry %+v after cancel", entry)
+	}
+}
+
+func TestStreamSortedDuplicatesMissingFile(t *testing.T) {
+	entries, errs := StreamSortedDuplicates(context.Background(), 1, "does-not-exist")
+	for range entries {
+		t.Error("unexpected entry")
+	}
+	if err := <-errs; err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestStreamNegativeThreshold(t *testing.T) {
+	f := writeTestFile(t, t.TempDir(), "a", "x\nx\n")
+	for name, stream := range map[string]func() (<-chan DuplicateEntry, <-chan error){
+		"StreamDuplicates": func() (<-chan DuplicateEntry, <-chan error) { return StreamDuplicates(context.Background(), -1, f) },
+		"StreamSortedDuplicates": func() (<-chan DuplicateEntry, <-chan error) {
+			return StreamSortedDuplicates(context.Background(), -1, f)
+		},
+	} {
+		entries, errs := stream()
+		for range entries {
+			t.Errorf("%s: unexpected entry", name)
+		}
+		if err := <-errs; !errors.Is(err, ErrNegativeThreshold) {
+			t.Errorf("%s: err = %v, want ErrNegativeThreshold", name, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Run summary
+
+	CI logs want one line per run that grep can pick out, whatever the report on stdout looks like.
+	With WithSummary, Detect ends by writing
+
+		scanned=12000 duplicates=42 errors=1 duration=350ms
+
+	scanned counting lines read, duplicates the lines reported, errors the files that couldn't be
+	read (or a refused threshold), duration the wall time of the run. The lines are tallied from
+	the progress reports of the readers, so they cost nothing more than /progress does.
+**/
+
+package exercises
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// runTally counts what a Detect run did, for its summary; a nil tally counts nothing
+type runTally struct {
+	start      time.Time
+	lines      atomic.Int64
+	duplicates atomic.Int64
+	errors     atomic.Int64
+	next       func(progressEvent) // the progress hook the tally was put in front of
+}
+
+func newRunTally(next func(progressEvent)) *runTally {
+	return &runTally{start: time.Now(), next: next}
+}
+
+func (t *runTally) progress(ev progressEvent) {
+	t.lines.Add(int64(ev.lines))
+	if t.next != nil {
+		t.next(ev)
+	}
+}
+
+func (t *runTally) reported(n int) {
+	if t != nil {
+		t.duplicates.Add(int64(n))
+	}
+}
+
+func (t *runTally) failed() {
+	if t != nil {
+		t.errors.Add(1)
+	}
+}
+
+func (t *runTally) write(w io.Writer) {
+	fmt.Fprintf(w, "scanned=%d duplicates=%d errors=%d duration=%s\n", t.lines.Load(), t.duplicates.Load(),
+		t.errors.Load(), time.Since(t.start).Round(time.Millisecond))
+}
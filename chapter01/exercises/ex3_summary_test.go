@@ -0,0 +1,56 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Run summary
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSummary(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a", "x\ny\nx\nz\n")
+	b := writeTestFile(t, dir, "b", "y\nw\n")
+	sorted := writeTestFile(t, dir, "sorted", "x\nx\ny\n")
+	missing := filepath.Join(dir, "missing")
+
+	format := regexp.MustCompile(`^scanned=(\d+) duplicates=(\d+) errors=(\d+) duration=\S+\n$`)
+	for _, tc := range []struct {
+		name    string
+		files   []string
+		options []DetectOption
+		want    string // scanned, duplicates and errors
+	}{
+		{"files", []string{a, b}, nil, "6 2 0"},
+		{"missing file", []string{a, missing}, nil, "4 1 1"},
+		{"threshold", []string{a, b}, []DetectOption{WithThreshold(2)}, "6 0 0"},
+		{"sorted", []string{sorted}, []DetectOption{WithSorted(true)}, "3 1 0"},
+		{"negative threshold", []string{a}, []DetectOption{WithThreshold(-1)}, "0 0 1"},
+	} {
+		var stderr bytes.Buffer
+		options := append(tc.options, WithSummary(&stderr), WithOutput(io.Discard), WithWarnings(io.Discard))
+		Detect(tc.files, options...)
+
+		m := format.FindStringSubmatch(stderr.String())
+		if m == nil {
+			t.Errorf("%s: summary %q, want one scanned=N duplicates=M errors=K duration=D line", tc.name, stderr.String())
+			continue
+		}
+		if got := strings.Join(m[1:], " "); got != tc.want {
+			t.Errorf("%s: scanned, duplicates, errors %s, want %s", tc.name, got, tc.want)
+		}
+	}
+
+	var stderr bytes.Buffer
+	Detect([]string{a}, WithOutput(&stderr), WithWarnings(&stderr))
+	if strings.Contains(stderr.String(), "scanned=") {
+		t.Errorf("summary without WithSummary: %q", stderr.String())
+	}
+}
@@ -0,0 +1,943 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Difficulty: Hard
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"unicode/utf8"
+)
+
+// truncatingHasher keeps only the first n bytes, so long lines sharing a prefix collide
+type truncatingHasher struct {
+	n int
+}
+
+func (h truncatingHasher) Hash(s string) string {
+	return s[:h.n]
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCollisionWarning(t *testing.T) {
+	prefix := strings.Repeat("x", 32)
+	f := writeTestFile(t, t.TempDir(), "a", prefix+"-first\n"+prefix+"-second\n"+prefix+"-first\n")
+
+	var warn bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.hasher = truncatingHasher{n: 32}
+	opts.checkCollisions = true
+	opts.warn = &warn
+
+	counts := countLines(opts, f)
+	if got := counts[prefix].count; got != 3 {
+		t.Errorf("count = %d, want 3 (collisions are still merged)", got)
+	}
+	want := "key collision between " + f + ":1 and " + f + ":2"
+	if !strings.Contains(warn.String(), want) {
+		t.Errorf("warning %q does not contain %q", warn.String(), want)
+	}
+	if n := strings.Count(warn.String(), "collision"); n != 1 {
+		t.Errorf("got %d warnings, want 1: %q", n, warn.String())
+	}
+}
+
+func TestNoCollisionWarningWithoutCheck(t *testing.T) {
+	prefix := strings.Repeat("x", 32)
+	f := writeTestFile(t, t.TempDir(), "a", prefix+"-first\n"+prefix+"-second\n")
+
+	var warn bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.hasher = truncatingHasher{n: 32}
+	opts.warn = &warn
+
+	countLines(opts, f)
+	if warn.Len() != 0 {
+		t.Errorf("unexpected warning %q", warn.String())
+	}
+}
+
+// shortHasher keeps 16 hex digits of sha256, shorter than the raw keys can be
+type shortHasher struct{}
+
+func (shortHasher) Hash(s string) string {
+	return hashString(s)[:16]
+}
+
+// A short line spelling out the digest of a long one must not be counted as that long line
+func TestRawKeyNeverEqualsHashedKey(t *testing.T) {
+	long := strings.Repeat("a long line, hashed rather than kept raw ", 2)
+	for _, hasher := range []Hasher{sha256Hasher{}, shortHasher{}} {
+		digest := hasher.Hash(long)
+		f := writeTestFile(t, t.TempDir(), "a", long+"\n"+digest+"\n"+long+"\n"+digest+"\n")
+
+		opts := defaultDupOptions(1)
+		opts.hasher = hasher
+		var got [][]int
+		for _, lineDatum := range countLines(opts, f) {
+			got = append(got, lineDatum.locations[f])
+		}
+		slices.SortFunc(got, slices.Compare)
+		if want := [][]int{{1, 3}, {2, 4}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%T: lines counted together %v, want %v", hasher, got, want)
+		}
+	}
+}
+
+func TestMaxKeyBytesSwitchesToHashing(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	sb.WriteString("line 7\nline 199\nline 7\n")
+	f := writeTestFile(t, t.TempDir(), "a", sb.String())
+
+	var warn bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.maxKeyBytes = 100
+	opts.warn = &warn
+
+	counts := countLines(opts, f)
+	if !strings.Contains(warn.String(), "hashing all lines") {
+		t.Errorf("expected a mode switch warning, got %q", warn.String())
+	}
+	if len(counts) != 200 {
+		t.Errorf("got %d distinct keys, want 200", len(counts))
+	}
+	for key := range counts {
+		if len(key) < maxRawKeyLen {
+			t.Fatalf("raw key %q survived the switch to hashing", key)
+		}
+	}
+	if got := counts[hashString("line 7")].count; got != 3 {
+		t.Errorf("line 7 count = %d, want 3", got)
+	}
+	if got := counts[hashString("line 199")].count; got != 2 {
+		t.Errorf("line 199 count = %d, want 2", got)
+	}
+	if got := counts[hashString("line 0")].count; got != 1 {
+		t.Errorf("line 0 count = %d, want 1", got)
+	}
+}
+
+func TestGroupByFileOrder(t *testing.T) {
+	dir := t.TempDir()
+	c := writeTestFile(t, dir, "c.log", "shared\nonly c\nonly c\n")
+	a := writeTestFile(t, dir, "a.log", "shared\nunique\n")
+	b := writeTestFile(t, dir, "b.log", "shared\n")
+
+	var out bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.groupByFile = true
+	opts.out = &out
+
+	files := []string{c, a, b}
+	printCounts(countLines(opts, files...), &opts, files)
+
+	got := out.String()
+	want := "== " + c + " ==\n" +
+		"3\tshared\tlineNums: [1]\n" +
+		"2\tonly c\tlineNums: [2 3]\n" +
+		"== " + a + " ==\n" +
+		"3\tshared\tlineNums: [1]\n" +
+		"== " + b + " ==\n" +
+		"3\tshared\tlineNums: [1]\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"a bit too long", 10, "a bit too…"},
+		{"日本語のテキストです", 5, "日本語の…"},
+		{"日本語", 1, "…"},
+	} {
+		if got := truncateRunes(tc.in, tc.n); got != tc.want {
+			t.Errorf("truncateRunes(%q, %d) = %q, want %q", tc.in, tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestMaxDisplayWidth(t *testing.T) {
+	long := strings.Repeat("日本語", 100)
+	f := writeTestFile(t, t.TempDir(), "a", long+"\n"+long+"\n"+long+"x\n")
+
+	var out bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.maxDisplayWidth = 8
+	opts.out = &out
+
+	counts := countLines(opts, f)
+	printCounts(counts, &opts, []string{f})
+
+	// Counting is still on the full line, so the "x" variant is a different key
+	if got := counts[hashString(long)].count; got != 2 {
+		t.Errorf("count = %d, want 2", got)
+	}
+	want := "2\t日本語日本語日…\n"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("output %q does not contain %q", out.String(), want)
+	}
+	if !utf8.ValidString(out.String()) {
+		t.Errorf("output is not valid UTF-8: %q", out.String())
+	}
+}
+
+func BenchmarkKeyTypes(b *testing.B) {
+	// Long lines so every key goes through the hasher; 1 in 4 lines is a repeat
+	var lines []string
+	for i := 0; i < 10000; i++ {
+		lines = append(lines, fmt.Sprintf("%s request %d served in %dms", strings.Repeat("-", 32), i%7500, i%100))
+	}
+
+	b.Run("string", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			counts := make(map[string]int)
+			for _, line := range lines {
+				counts[hashString(line)]++
+			}
+		}
+	})
+
+	b.Run("bytes32", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			counts := make(map[[32]byte]int)
+			for _, line := range lines {
+				counts[sha256.Sum256([]byte(line))]++
+			}
+		}
+	})
+}
+
+func TestRuneLengths(t *testing.T) {
+	const (
+		combining = "cafe\u0301" // 5 runes, 6 bytes, reads as 4 characters
+		emoji     = "👍🏽👍🏽"       // 4 runes (thumb + skin tone, twice), 16 bytes
+	)
+	for _, tc := range []struct {
+		in    string
+		n     int
+		trunc string
+	}{
+		{combining, 5, "café"},
+		{combining, 4, "caf…"},
+		{emoji, 4, emoji},
+		{emoji, 3, "👍🏽…"},
+		{emoji, 2, "👍…"}, // the modifier is a separate rune and gets cut
+	} {
+		if got := truncateRunes(tc.in, tc.n); got != tc.trunc {
+			t.Errorf("truncateRunes(%q, %d) = %q, want %q", tc.in, tc.n, got, tc.trunc)
+		}
+	}
+
+	f := writeTestFile(t, t.TempDir(), "a", strings.Join([]string{
+		combining, combining, // 5 runes, kept
+		emoji, emoji, // 4 runes, kept
+		"日本", "日本", // 2 runes but 6 bytes, dropped
+	}, "\n")+"\n")
+	opts := defaultDupOptions(1)
+	opts.minLineLength = 4
+	counts := countLines(opts, f)
+	if got := counts[combining].count; got != 2 {
+		t.Errorf("%q count = %d, want 2", combining, got)
+	}
+	if got := counts[emoji].count; got != 2 {
+		t.Errorf("%q count = %d, want 2", emoji, got)
+	}
+	if _, ok := counts["日本"]; ok {
+		t.Errorf("%q is 2 runes and should be below minLineLength", "日本")
+	}
+	// Skipped lines still count towards the line numbers
+	if !reflect.DeepEqual(counts[emoji].locations[f], []int{3, 4}) {
+		t.Errorf("%q locations = %v, want [3 4]", emoji, counts[emoji].locations[f])
+	}
+}
+
+func TestPerFile(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a.log", "retry\nretry\nshared\n")
+	b := writeTestFile(t, dir, "b.log", "shared\nretry\n")
+
+	var out bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.perFile = true
+	opts.out = &out
+	detectFiles(opts, a, b)
+
+	// "shared" is only repeated across files, and b has no internal repeats at all
+	want := "== " + a + " ==\n" +
+		"2\tretry\tlineNums: [1 2]\n" +
+		"== " + b + " ==\n"
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestNormalizeUnicode(t *testing.T) {
+	const (
+		precomposed = "caf\u00e9"
+		combining   = "cafe\u0301"
+	)
+	f := writeTestFile(t, t.TempDir(), "a", precomposed+"\n"+combining+"\n")
+
+	counts := countLines(defaultDupOptions(1), f)
+	if len(counts) != 2 {
+		t.Errorf("without normalization got %d keys, want 2", len(counts))
+	}
+
+	var out bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.normalizeUnicode = true
+	opts.out = &out
+	counts = countLines(opts, f)
+	if got := counts[precomposed].count; got != 2 || len(counts) != 1 {
+		t.Errorf("with normalization got %v, want both forms under %q", counts, precomposed)
+	}
+	printCounts(counts, &opts, []string{f})
+	if !strings.Contains(out.String(), "2\t"+precomposed+"\n") {
+		t.Errorf("output %q should report the NFC form", out.String())
+	}
+
+	// reportRaw shows the first line as it was read
+	out.Reset()
+	opts.reportRaw = true
+	printCounts(countLines(opts, f), &opts, []string{f})
+	if !strings.Contains(out.String(), "2\t"+precomposed+"\n") {
+		t.Errorf("output %q should report the first raw line", out.String())
+	}
+	f = writeTestFile(t, t.TempDir(), "b", combining+"\n"+precomposed+"\n")
+	out.Reset()
+	printCounts(countLines(opts, f), &opts, []string{f})
+	if !strings.Contains(out.String(), "2\t"+combining+"\n") {
+		t.Errorf("output %q should report the first raw line", out.String())
+	}
+}
+
+func TestWithOffsets(t *testing.T) {
+	content := "header\r\nduplicate me\n\nsomething else\r\nduplicate me\nduplicate me"
+	f := writeTestFile(t, t.TempDir(), "a", content)
+
+	opts := defaultDupOptions(1)
+	opts.withOffsets = true
+	lineDatum := countLines(opts, f)["duplicate me"]
+
+	offsets := lineDatum.offsets[f]
+	if len(offsets) != 3 {
+		t.Fatalf("offsets = %v, want 3 of them", offsets)
+	}
+	data, err := os.ReadFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, off := range offsets {
+		if !strings.HasPrefix(string(data[off:]), "duplicate me") {
+			t.Errorf("offset %d (line %d) points at %q", off, lineDatum.locations[f][i], data[off:])
+		}
+	}
+	if want := int64(strings.Index(content, "duplicate me")); offsets[0] != want {
+		t.Errorf("first offset = %d, want %d", offsets[0], want)
+	}
+
+	if countLines(defaultDupOptions(1), f)["duplicate me"].offsets != nil {
+		t.Error("offsets recorded without withOffsets")
+	}
+}
+
+func TestMinPerFileCount(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := 0; i < 5; i++ {
+		// "thin" once per file, 5 in total; "hammered" 3 times, all in the first file
+		content := "thin\n"
+		if i == 0 {
+			content += "hammered\nhammered\nhammered\n"
+		}
+		files = append(files, writeTestFile(t, dir, fmt.Sprintf("%d.log", i), content))
+	}
+
+	opts := defaultDupOptions(2)
+	counts := countLines(opts, files...)
+	if !opts.reported(counts["thin"]) || !opts.reported(counts["hammered"]) {
+		t.Errorf("with the global threshold both lines should be reported")
+	}
+
+	opts.minPerFileCount = 2
+	if opts.reported(counts["thin"]) {
+		t.Errorf("thin is never repeated within a file and should not be reported")
+	}
+	if !opts.reported(counts["hammered"]) {
+		t.Errorf("hammered is repeated within one file and should be reported")
+	}
+
+	var out bytes.Buffer
+	opts.out = &out
+	printCounts(counts, &opts, files)
+	if strings.Contains(out.String(), "thin") || !strings.Contains(out.String(), "3\thammered\n") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
+
+func TestMinFiles(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := 0; i < 3; i++ {
+		// "shared" once in each file; "local" 10 times, all in the first file
+		content := "shared\n"
+		if i == 0 {
+			content += strings.Repeat("local\n", 10)
+		}
+		files = append(files, writeTestFile(t, dir, fmt.Sprintf("%d.log", i), content))
+	}
+
+	report, err := DetectReportWith(files, WithMinFiles(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DuplicateEntry{
+		{Text: "shared", Count: 3, Locations: map[string][]int{files[0]: {1}, files[1]: {1}, files[2]: {1}}},
+	}
+	if !reflect.DeepEqual(report.Entries, want) {
+		t.Errorf("min files 2: %+v, want %+v", report.Entries, want)
+	}
+
+	report, err = DetectReportWith(files, WithMinFiles(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Entries) != 0 {
+		t.Errorf("min files 4: %+v, want nothing", report.Entries)
+	}
+}
+
+func TestNormalizeNumbers(t *testing.T) {
+	f := writeTestFile(t, t.TempDir(), "a", "processed 100 records\n"+
+		"processed 250 records\n"+
+		"processed 7 files\n"+
+		"retry 3 of 5\n"+
+		"retry 12 of 50\n"+
+		"processed 9 records\n")
+
+	report, err := DetectReportWith([]string{f}, WithNormalizeNumbers(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DuplicateEntry{
+		{Text: "processed # records", Count: 3, Locations: map[string][]int{f: {1, 2, 6}}},
+		{Text: "retry # of #", Count: 2, Locations: map[string][]int{f: {4, 5}}},
+	}
+	if !reflect.DeepEqual(report.Entries, want) {
+		t.Errorf("normalized numbers: %+v, want %+v", report.Entries, want)
+	}
+
+	for in, want := range map[string]string{
+		"":               "",
+		"no digits":      "no digits",
+		"42":             "#",
+		"v1.2.3-rc10":    "v#.#.#-rc#",
+		"id=007, n=٣":    "id=#, n=٣",
+		"2024-03-01T12Z": "#-#-#T#Z",
+	} {
+		if got := maskNumbers(in); got != want {
+			t.Errorf("maskNumbers(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestIgnoreRegex(t *testing.T) {
+	f := writeTestFile(t, t.TempDir(), "a", "2024-03-01T12:00:00Z GET /health 200\n"+
+		"GET /health 200 2024-03-01T12:00:05Z\n"+
+		"2024-03-01T12:00:09Z GET /health 500\n"+
+		"2024-03-02T08:30:00Z GET /health 200\n")
+
+	timestamp := regexp.MustCompile(`\s*\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ\s*`)
+	report, err := DetectReportWith([]string{f}, WithIgnoreRegex(timestamp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Reported by the first of them, as read
+	want := []DuplicateEntry{
+		{Text: "2024-03-01T12:00:00Z GET /health 200", Count: 3, Locations: map[string][]int{f: {1, 2, 4}}},
+	}
+	if !reflect.DeepEqual(report.Entries, want) {
+		t.Errorf("timestamps ignored: %+v, want %+v", report.Entries, want)
+	}
+}
+
+func TestLocationsCap(t *testing.T) {
+	f := writeTestFile(t, t.TempDir(), "a", strings.Repeat("again\n", 1000)+"other\nother\n")
+
+	var out bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.locationsCap = 10
+	opts.out = &out
+	counts := countLines(opts, f)
+
+	again := counts["again"]
+	if again.count != 1000 {
+		t.Errorf("count = %d, want 1000", again.count)
+	}
+	if !again.truncated {
+		t.Error("truncated flag not set")
+	}
+	if !reflect.DeepEqual(again.locations[f], []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}) {
+		t.Errorf("locations = %v, want the first 10", again.locations[f])
+	}
+	if other := counts["other"]; other.truncated || len(other.locations[f]) != 2 {
+		t.Errorf("other = %+v, should be untouched by the cap", other)
+	}
+
+	printCounts(counts, &opts, []string{f})
+	if strings.Count(out.String(), "locations truncated") != 1 {
+		t.Errorf("output %q should note the truncation once", out.String())
+	}
+}
+
+func TestLineOrder(t *testing.T) {
+	f := writeTestFile(t, t.TempDir(), "a", "pear\nfig\napple\nfig\npear\napple\nkiwi\nkiwi\nfig\n")
+
+	for _, tc := range []struct {
+		name  string
+		order LineOrder
+		want  []string
+	}{
+		{"count", OrderByCount, []string{"fig", "apple", "kiwi", "pear"}},
+		{"text", OrderByText, []string{"apple", "fig", "kiwi", "pear"}},
+		{"first-seen", OrderByFirstSeen, []string{"pear", "fig", "apple", "kiwi"}},
+	} {
+		if order, err := ParseLineOrder(tc.name); order != tc.order || err != nil {
+			t.Errorf("ParseLineOrder(%q) = %d, %v", tc.name, order, err)
+		}
+		opts := defaultDupOptions(1)
+		opts.order = tc.order
+		var got []string
+		for _, entry := range newDuplicateReport(countLines(opts, f), &opts).Entries {
+			got = append(got, entry.Text)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("order %d: got %v, want %v", tc.order, got, tc.want)
+		}
+	}
+	if _, err := ParseLineOrder("size"); err == nil {
+		t.Error("expected an error for an unknown order")
+	}
+}
+
+// TestConcurrentAccumulation guards the reader goroutines and the counter against data races. It
+// only proves anything under the race detector, which make test and make ci-test turn on; keep it
+// in the race CI run, especially when changing how lines are read, hashed or counted in parallel.
+func TestConcurrentAccumulation(t *testing.T) {
+	const nFiles, nLines = 32, 200
+	dir := t.TempDir()
+	var files []string
+	for i := 0; i < nFiles; i++ {
+		var sb strings.Builder
+		for j := 0; j < nLines; j++ {
+			// Every file shares "common", half the lines are shared by every other file
+			fmt.Fprintf(&sb, "common\nline %d of parity %d\n", j, i%2)
+		}
+		files = append(files, writeTestFile(t, dir, fmt.Sprintf("%02d.log", i), sb.String()))
+	}
+
+	var progressLines atomic.Int64
+	opts := defaultDupOptions(1)
+	opts.withOffsets = true
+	opts.progress = func(ev progressEvent) { progressLines.Add(int64(ev.lines)) }
+	opts.out = io.Discard
+
+	for run := 0; run < 3; run++ {
+		counts := countLines(opts, files...)
+		if len(counts) != 1+2*nLines {
+			t.Fatalf("got %d distinct lines, want %d", len(counts), 1+2*nLines)
+		}
+		if got := counts["common"].count; got != nFiles*nLines {
+			t.Errorf("common count = %d, want %d", got, nFiles*nLines)
+		}
+		if got := counts["line 7 of parity 1"].count; got != nFiles/2 {
+			t.Errorf("line 7 count = %d, want %d", got, nFiles/2)
+		}
+		for _, f := range files {
+			if got := len(counts["common"].locations[f]); got != nLines {
+				t.Errorf("%s has %d locations for common, want %d", f, got, nLines)
+			}
+		}
+		detectFiles(opts, files...)
+	}
+	if got, want := progressLines.Load(), int64(2*3*nFiles*2*nLines); got != want {
+		t.Errorf("progress reported %d lines, want %d", got, want)
+	}
+}
+
+func TestCasingBreakdown(t *testing.T) {
+	f := writeTestFile(t, t.TempDir(), "a", "ERROR\nError\nwarn\nERROR\nWarn\nERROR\n")
+
+	var out bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.caseInsensitive = true
+	opts.casingBreakdown = true
+	opts.out = &out
+	counts := countLines(opts, f)
+
+	if got, want := counts["error"].casings, map[string]int{"ERROR": 3, "Error": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("error casings = %v, want %v", got, want)
+	}
+	if got, want := counts["warn"].casings, map[string]int{"warn": 1, "Warn": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("warn casings = %v, want %v", got, want)
+	}
+	if got := counts["error"].count; got != 4 {
+		t.Errorf("error count = %d, want 4", got)
+	}
+
+	printCounts(counts, &opts, []string{f})
+	if want := "\tCasings: \"ERROR\":3, \"Error\":1\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("output %q does not contain %q", out.String(), want)
+	}
+
+	// Without caseInsensitive there is nothing to break down
+	opts.caseInsensitive = false
+	if casings := countLines(opts, f)["ERROR"].casings; casings != nil {
+		t.Errorf("casings = %v without caseInsensitive", casings)
+	}
+}
+
+func TestPreprocess(t *testing.T) {
+	f := writeTestFile(t, t.TempDir(), "a", "2024-03-01T10:00:00Z disk full\n"+
+		"2024-03-01T10:05:12Z disk full\n"+
+		"2024-03-02T08:00:00.123+02:00 disk full\n"+
+		"2024-03-02T08:00:01Z disk almost full\n")
+	timestamp := regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T[\d:.]+(Z|[+-]\d{2}:\d{2}) `)
+
+	var out bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.preprocess = func(s string) string { return timestamp.ReplaceAllString(s, "") }
+	opts.out = &out
+	counts := countLines(opts, f)
+
+	if got := counts["disk full"].count; got != 3 {
+		t.Errorf("disk full count = %d, want 3", got)
+	}
+	if got := counts["disk almost full"].count; got != 1 {
+		t.Errorf("disk almost full count = %d, want 1", got)
+	}
+	printCounts(counts, &opts, []string{f})
+	if want := "3\t2024-03-01T10:00:00Z disk full\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("output %q should report the first original line %q", out.String(), want)
+	}
+}
+
+func TestBinaryInput(t *testing.T) {
+	dir := t.TempDir()
+	blob := "\x7fELF\x00\x01\x02\n"
+	bin := writeTestFile(t, dir, "a.out", blob+blob+"\xff\xfe\x00\n")
+	text := writeTestFile(t, dir, "a.log", "hello\nhello\n")
+
+	var out, warn bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.out, opts.warn = &out, &warn
+	counts := countLines(opts, bin, text)
+	if len(counts) != 1 || counts["hello"].count != 2 {
+		t.Errorf("counts = %v, want only the text file's lines", counts)
+	}
+	if !strings.Contains(warn.String(), bin+" looks binary, skipping it") {
+		t.Errorf("warning %q should name the skipped file", warn.String())
+	}
+
+	opts.binaryInput = hashBinary
+	counts = countLines(opts, bin, text)
+	key := hashString(strings.TrimSuffix(blob, "\n"))
+	if got := counts[key].count; got != 2 {
+		t.Errorf("binary line count = %d, want 2 under its hash", got)
+	}
+	if got := counts["hello"].count; got != 2 {
+		t.Errorf("text lines should still be keyed as is, hello count = %d", got)
+	}
+	printCounts(counts, &opts, []string{bin, text})
+	if strings.ContainsRune(out.String(), 0) || strings.Contains(out.String(), "ELF") {
+		t.Errorf("binary bytes leaked into the output %q", out.String())
+	}
+	if want := "2\t<binary line " + key[:12] + ", 7 bytes>\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("output %q does not contain %q", out.String(), want)
+	}
+}
+
+func TestExcludeFile(t *testing.T) {
+	dir := t.TempDir()
+	header := "// Copyright 2024 The Authors. All rights reserved."
+	exclude := writeTestFile(t, dir, "exclude", header+"\n}\n")
+	a := writeTestFile(t, dir, "a.go", header+"\nfunc a() {\n}\nreturn err\nreturn err\n")
+	b := writeTestFile(t, dir, "b.go", strings.ToUpper(header)+"\nfunc b() {\n}\n")
+
+	var out bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.excludeFile = exclude
+	opts.caseInsensitive = true
+	opts.out = &out
+	counts := countLines(opts, a, b)
+
+	if len(counts) != 3 {
+		t.Errorf("got %d distinct lines, want 3 (func a, func b, return err): %v", len(counts), counts)
+	}
+	if _, ok := counts[hashString(strings.ToLower(header))]; ok {
+		t.Error("header was counted, in either casing")
+	}
+	if got := counts["return err"]; got.count != 2 || !reflect.DeepEqual(got.locations[a], []int{4, 5}) {
+		t.Errorf("return err = %+v, want lines 4 and 5 of a", got)
+	}
+	printCounts(counts, &opts, []string{a, b})
+	if strings.Contains(out.String(), "Copyright") || strings.Contains(out.String(), "}") {
+		t.Errorf("excluded lines were reported: %q", out.String())
+	}
+
+	var warn bytes.Buffer
+	opts.excludeFile = filepath.Join(dir, "missing")
+	opts.warn = &warn
+	if got := countLines(opts, a)["}"].count; got != 1 {
+		t.Errorf("without a readable exclude file nothing is excluded, } count = %d", got)
+	}
+	if !strings.Contains(warn.String(), "can't read exclude file") {
+		t.Errorf("warning %q should mention the exclude file", warn.String())
+	}
+}
+
+func TestHeadLimit(t *testing.T) {
+	var sb strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i%5)
+	}
+	f := writeTestFile(t, t.TempDir(), "a", sb.String())
+
+	opts := defaultDupOptions(1)
+	opts.headLimit = 10
+	counts := countLines(opts, f)
+
+	total := 0
+	for _, lineDatum := range counts {
+		total += lineDatum.count
+	}
+	if total != 10 {
+		t.Errorf("counted %d lines, want the first 10", total)
+	}
+	if got := counts["line 1"].locations[f]; !reflect.DeepEqual(got, []int{1, 6}) {
+		t.Errorf("line 1 locations = %v, want [1 6]", got)
+	}
+}
+
+func TestKeyPrefixLen(t *testing.T) {
+	const prefix = "2024-03-01 GET /api/orders 200 "
+	f := writeTestFile(t, t.TempDir(), "a", prefix+`{"id":1,"items":[1,2,3]}`+"\n"+
+		prefix+`{"id":2,"items":[]}`+"\n"+
+		"2024-03-01 GET /api/users 200 {}\n")
+
+	counts := countLines(defaultDupOptions(1), f)
+	if len(counts) != 3 {
+		t.Errorf("without the option got %d distinct lines, want 3", len(counts))
+	}
+
+	var out bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.keyPrefixLen = len(prefix)
+	opts.out = &out
+	counts = countLines(opts, f)
+	if len(counts) != 2 {
+		t.Errorf("got %d distinct lines, want 2", len(counts))
+	}
+	if got := counts[getKey(prefix, sha256Hasher{})].count; got != 2 {
+		t.Errorf("prefix count = %d, want 2", got)
+	}
+	printCounts(counts, &opts, []string{f})
+	if want := "2\t" + prefix + `{"id":1,"items":[1,2,3]}` + "\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("output %q should report the first full line", out.String())
+	}
+
+	for _, tc := range []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"abcdef", 3, "abc"},
+		{"日本語", 4, "日"}, // 3 byte runes, the second one doesn't fit
+		{"日本語", 6, "日本"},
+	} {
+		if got := bytePrefix(tc.in, tc.n); got != tc.want {
+			t.Errorf("bytePrefix(%q, %d) = %q, want %q", tc.in, tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestUniqCountFormat(t *testing.T) {
+	f := writeTestFile(t, t.TempDir(), "a", strings.Repeat("many\n", 12)+"few\nfew\n")
+
+	var out bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.countWidth = uniqCountWidth
+	opts.countSep = " "
+	opts.out = &out
+	counts := countLines(opts, f)
+	printCounts(counts, &opts, []string{f})
+	// Same columns as `uniq -c`
+	for _, want := range []string{"\n     12 many\n", "\n      2 few\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output %q lacks %q", out.String(), want)
+		}
+	}
+
+	opts = defaultDupOptions(1)
+	if got := opts.formatCount(12); got != "12\t" {
+		t.Errorf("default count column = %q, want %q", got, "12\t")
+	}
+	opts.countWidth = 3
+	opts.countSep = "|"
+	if got := opts.formatCount(7); got != "  7|" {
+		t.Errorf("count column = %q, want %q", got, "  7|")
+	}
+	if got := opts.formatCount(1234); got != "1234|" {
+		t.Errorf("wider count column = %q, want %q", got, "1234|")
+	}
+}
+
+func TestCompactRanges(t *testing.T) {
+	for _, tc := range []struct {
+		in   []int
+		want string
+	}{
+		{nil, ""},
+		{[]int{7}, "7"},
+		{[]int{10, 11, 12, 13}, "10-13"},
+		{[]int{1, 3, 5}, "1,3,5"},
+		{[]int{10, 11, 12, 13, 20, 25, 26, 27}, "10-13,20,25-27"},
+		{[]int{4, 5, 1, 2}, "1-2,4-5"},
+	} {
+		if got := compactRanges(tc.in); got != tc.want {
+			t.Errorf("compactRanges(%v) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+
+	f := writeTestFile(t, t.TempDir(), "a", "x\nx\nx\ny\nx\n")
+	var out bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.compactLocations = true
+	opts.out = &out
+	printCounts(countLines(opts, f), &opts, []string{f})
+	if want := "\tFileName: " + f + ", lineNums: 1-3,5\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("output %q lacks %q", out.String(), want)
+	}
+}
+
+func TestCountOnly(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a", "x\ny\nx\nz\nx\n")
+	b := writeTestFile(t, dir, "b", "y\nw\nx\n")
+
+	full := defaultDupOptions(1)
+	want := summarize(countLines(full, a, b), &full)
+	if want != (dupSummary{lines: 2, redundant: 4}) {
+		t.Fatalf("full mode summary = %+v, want 2 lines, 4 redundant", want)
+	}
+
+	var out bytes.Buffer
+	opts := defaultDupOptions(1)
+	opts.countOnly = true
+	opts.out = &out
+	counts := countLines(opts, a, b)
+	if got := summarize(counts, &opts); got != want {
+		t.Errorf("count only summary = %+v, want %+v", got, want)
+	}
+	for line, lineDatum := range counts {
+		if lineDatum.locations != nil || lineDatum.offsets != nil {
+			t.Errorf("%q kept locations %v", line, lineDatum.locations)
+		}
+	}
+	printCounts(counts, &opts, []string{a, b})
+	if got := out.String(); got != "2 duplicated lines, 4 redundant occurrences\n" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestFNVHasher(t *testing.T) {
+	long := strings.Repeat("a fairly long log line ", 3)
+	key := getKey(long, fnvHasher{})
+	if len(key) < maxRawKeyLen {
+		t.Errorf("fnv key %q is short enough to pass for a raw line", key)
+	}
+	if key != getKey(long, fnvHasher{}) || key == getKey(long+".", fnvHasher{}) {
+		t.Error("fnv keys aren't deterministic or don't tell lines apart")
+	}
+
+	f := writeTestFile(t, t.TempDir(), "a", long+"\nshort\n"+long+"\n")
+	opts := defaultDupOptions(1)
+	opts.hasher = fnvHasher{}
+	if got := countLines(opts, f)[key].count; got != 2 {
+		t.Errorf("count under the fnv key = %d, want 2", got)
+	}
+}
+
+func BenchmarkHashers(b *testing.B) {
+	line := strings.Repeat("2024-03-01T12:00:00Z GET /api/orders 200 ", 3)
+	for _, h := range []struct {
+		name   string
+		hasher Hasher
+	}{
+		{"sha256", sha256Hasher{}},
+		{"fnv", fnvHasher{}},
+	} {
+		b.Run(h.name, func(b *testing.B) {
+			b.SetBytes(int64(len(line)))
+			for i := 0; i < b.N; i++ {
+				getKey(line, h.hasher)
+			}
+		})
+	}
+}
+
+func TestContinuousLineNumbers(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a", "x\ny\nx\n")
+	b := writeTestFile(t, dir, "b", "\ny\n")
+	c := writeTestFile(t, dir, "c", "x\n")
+
+	opts := defaultDupOptions(1)
+	opts.continuousLineNumbers = true
+	opts.minLineLength = 1 // the empty line is skipped, but still takes up a number
+	opts.parallelFiles, opts.parallelHash = 4, 4
+	for range 5 {
+		counts := countLines(opts, a, b, c)
+		want := map[string]map[string][]int{
+			"x": {a: {1, 3}, c: {6}},
+			"y": {a: {2}, b: {5}},
+		}
+		for line, locations := range want {
+			if got := counts[line].locations; !reflect.DeepEqual(got, locations) {
+				t.Fatalf("%s: locations = %v, want %v", line, got, locations)
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Browsing a report in the terminal
+
+	Browse shows a DuplicateReport as a list in report order (most frequent first) that can be
+	walked with the arrow keys or j/k; enter opens the locations of the line under the cursor, and
+	/ types a filter that keeps only the lines containing it, ignoring case. The state and the key
+	handling live in browserModel.Update, apart from the rendering in View, so they can be tested
+	without a terminal.
+**/
+
+package exercises
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Browse runs the browser over r until the user quits with q, esc or ctrl+c
+func Browse(r *DuplicateReport) error {
+	_, err := tea.NewProgram(newBrowserModel(r), tea.WithAltScreen()).Run()
+	return err
+}
+
+type browserModel struct {
+	threshold int
+	entries   []DuplicateEntry
+	visible   []int // indexes into entries of the lines passing the filter
+	filter    string
+	filtering bool // keys go to the filter box
+	cursor    int  // index into visible
+	open      bool // the locations of the entry under the cursor are shown
+	height    int  // of the terminal, 0 until known
+}
+
+func newBrowserModel(r *DuplicateReport) browserModel {
+	m := browserModel{threshold: r.Threshold, entries: r.Entries}
+	m.applyFilter()
+	return m
+}
+
+func (m browserModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m browserModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+	case tea.KeyMsg:
+		if m.filtering {
+			return m.updateFilter(msg), nil
+		}
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		case "up", "k":
+			m.move(-1)
+		case "down", "j":
+			m.move(1)
+		case "pgup":
+			m.move(-m.pageSize())
+		case "pgdown":
+			m.move(m.pageSize())
+		case "home", "g":
+			m.move(-len(m.visible))
+		case "end", "G":
+			m.move(len(m.visible))
+		case "enter":
+			m.open = !m.open && len(m.visible) > 0
+		case "/":
+			m.filtering, m.open = true, false
+		}
+	}
+	return m, nil
+}
+
+// updateFilter edits the filter box; enter keeps the filter, esc drops it
+func (m browserModel) updateFilter(msg tea.KeyMsg) browserModel {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.filtering = false
+		return m
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.filtering, m.filter = false, ""
+	case tea.KeyBackspace:
+		if r := []rune(m.filter); len(r) > 0 {
+			m.filter = string(r[:len(r)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.filter += string(msg.Runes)
+	default:
+		return m
+	}
+	m.applyFilter()
+	return m
+}
+
+// applyFilter recomputes the visible entries, keeping the cursor on the same entry if it is
+// still visible and at the top otherwise
+func (m *browserModel) applyFilter() {
+	current := m.current()
+	needle := strings.ToLower(m.filter)
+	// A new slice, earlier copies of the model keep theirs
+	m.visible, m.cursor = nil, 0
+	for i, entry := range m.entries {
+		if strings.Contains(strings.ToLower(entry.Text), needle) {
+			if i == current {
+				m.cursor = len(m.visible)
+			}
+			m.visible = append(m.visible, i)
+		}
+	}
+}
+
+// current is the index into entries of the entry under the cursor, -1 if none
+func (m browserModel) current() int {
+	if m.cursor >= len(m.visible) {
+		return -1
+	}
+	return m.visible[m.cursor]
+}
+
+func (m *browserModel) move(delta int) {
+	m.cursor = max(0, min(m.cursor+delta, len(m.visible)-1))
+	m.open = false
+}
+
+// pageSize is how many entries fit the list, leaving room for the header and the filter box
+func (m browserModel) pageSize() int {
+	if m.height == 0 {
+		return 20
+	}
+	return max(1, m.height-3)
+}
+
+func (m browserModel) View() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d duplicate lines, threshold %d\n", len(m.visible), len(m.entries), m.threshold)
+	if m.open {
+		m.viewLocations(&b, m.entries[m.current()])
+	} else {
+		page := m.pageSize()
+		first := max(0, m.cursor-page+1)
+		for i := first; i < len(m.visible) && i < first+page; i++ {
+			marker := "  "
+			if i == m.cursor {
+				marker = "> "
+			}
+			entry := m.entries[m.visible[i]]
+			fmt.Fprintf(&b, "%s%7d %s\n", marker, entry.Count, truncateRunes(entry.Text, 120))
+		}
+	}
+	switch {
+	case m.filtering:
+		fmt.Fprintf(&b, "/%s█\n", m.filter)
+	case m.filter != "":
+		fmt.Fprintf(&b, "filter: %s  (/ to edit)  enter: locations  q: quit\n", m.filter)
+	default:
+		b.WriteString("↑/↓ move  enter: locations  /: filter  q: quit\n")
+	}
+	return b.String()
+}
+
+func (m browserModel) viewLocations(b *strings.Builder, entry DuplicateEntry) {
+	fmt.Fprintf(b, "%d\t%s\n", entry.Count, entry.Text)
+	if entry.File != "" {
+		fmt.Fprintf(b, "\t%s: lines %d-%d\n", entry.File, entry.Start, entry.End)
+		return
+	}
+	for _, fileName := range entry.fileNames() {
+		fmt.Fprintf(b, "\t%s: %s\n", fileName, compactRanges(entry.Locations[fileName]))
+	}
+	if entry.Truncated {
+		b.WriteString("\t(locations truncated)\n")
+	}
+}
@@ -0,0 +1,92 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Browsing a report in the terminal
+**/
+
+package exercises
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func press(t *testing.T, m browserModel, keys ...tea.KeyMsg) browserModel {
+	t.Helper()
+	for _, key := range keys {
+		next, _ := m.Update(key)
+		m = next.(browserModel)
+	}
+	return m
+}
+
+func runes(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestBrowserModel(t *testing.T) {
+	m := newBrowserModel(&DuplicateReport{Threshold: 1, Entries: []DuplicateEntry{
+		{Text: "GET /health", Count: 9, Locations: map[string][]int{"a": {1, 2, 3}}},
+		{Text: "POST /scan", Count: 5, Locations: map[string][]int{"a": {4}}},
+		{Text: "GET /counter", Count: 3, Locations: map[string][]int{"b": {7, 9}}},
+	}})
+
+	down, up, enter := tea.KeyMsg{Type: tea.KeyDown}, tea.KeyMsg{Type: tea.KeyUp}, tea.KeyMsg{Type: tea.KeyEnter}
+	m = press(t, m, down, down, down)
+	if got := m.entries[m.current()].Text; got != "GET /counter" {
+		t.Errorf("cursor past the end on %q, want the last line", got)
+	}
+	m = press(t, m, up, runes("k"), runes("k"))
+	if m.cursor != 0 {
+		t.Errorf("cursor = %d past the top, want 0", m.cursor)
+	}
+
+	m = press(t, m, enter)
+	if !m.open || !strings.Contains(m.View(), "a: 1-3") {
+		t.Errorf("enter didn't open the locations:\n%s", m.View())
+	}
+	m = press(t, m, enter)
+	if m.open {
+		t.Error("enter didn't close the locations")
+	}
+
+	// Filtering keeps the cursor on its line while that line is visible
+	m = press(t, m, runes("j"), runes("/"), runes("G"), runes("e"), runes("T"))
+	if !m.filtering || m.filter != "GeT" {
+		t.Fatalf("filter = %q, filtering %v; want GeT, still typing", m.filter, m.filtering)
+	}
+	if len(m.visible) != 2 || m.cursor != 0 || m.entries[m.current()].Text != "GET /health" {
+		t.Errorf("visible = %v, cursor %d, want the two GETs with the cursor on the first", m.visible, m.cursor)
+	}
+	m = press(t, m, runes(" /c"), enter)
+	if m.filtering || len(m.visible) != 1 || m.entries[m.current()].Text != "GET /counter" {
+		t.Errorf("filter %q: visible = %v, filtering %v", m.filter, m.visible, m.filtering)
+	}
+	// j is a key again once the filter is entered, not more filter text
+	m = press(t, m, runes("j"))
+	if m.filter != "GeT /c" {
+		t.Errorf("filter = %q after leaving the box", m.filter)
+	}
+
+	m = press(t, m, runes("/"), tea.KeyMsg{Type: tea.KeyBackspace}, tea.KeyMsg{Type: tea.KeyBackspace})
+	if m.filter != "GeT " || len(m.visible) != 2 || m.entries[m.current()].Text != "GET /counter" {
+		t.Errorf("after backspace: filter %q, visible %v, cursor %d", m.filter, m.visible, m.cursor)
+	}
+	m = press(t, m, runes("x"))
+	if len(m.visible) != 0 || m.current() != -1 {
+		t.Errorf("no match: visible %v, current %d", m.visible, m.current())
+	}
+	m = press(t, m, enter, enter)
+	if m.open {
+		t.Error("opened the locations of nothing")
+	}
+	m = press(t, m, runes("/"), tea.KeyMsg{Type: tea.KeyEsc})
+	if m.filter != "" || len(m.visible) != 3 {
+		t.Errorf("esc: filter %q, visible %v, want everything back", m.filter, m.visible)
+	}
+
+	if _, cmd := m.Update(runes("q")); cmd == nil {
+		t.Error("q doesn't quit")
+	}
+}
@@ -0,0 +1,229 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Input validation
+
+	A quick pass before a long scan: expand globs and directories, then stat and open every file
+	without reading its content. Missing, unreadable and empty files are reported together, as
+	ScanErrors, so a caller can tell which files failed and why and decide whether the rest is
+	worth scanning.
+	A pattern or directory that expands to more than maxFiles files is refused outright, before
+	any of them is opened: that is a glob of the wrong directory, not an input.
+	A negative threshold is refused by every detector too. A line is reported when seen more than
+	threshold times, so 0 reports every line and 1 the duplicates; below 0 there is nothing left to
+	mean.
+**/
+
+package exercises
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultMaxFiles is how many files ValidateInputs accepts
+const DefaultMaxFiles = 10000
+
+var ErrTooManyFiles = errors.New("too many input files")
+
+var ErrNegativeThreshold = errors.New("negative threshold")
+
+// checkThreshold refuses a threshold below 0
+func checkThreshold(threshold int) error {
+	if threshold < 0 {
+		return fmt.Errorf("%w %d: lines seen more than threshold times are reported, 0 reports all", ErrNegativeThreshold, threshold)
+	}
+	return nil
+}
+
+// Causes of a FileError besides the errors of the os and filepath packages
+var (
+	ErrMissingFile = errors.New("missing")
+	ErrEmptyFile   = errors.New("empty")
+	ErrNotRegular  = errors.New("not a regular file")
+	ErrNoMatch     = errors.New("no files match")
+)
+
+// FileError is an input that can't be scanned, File being the path or pattern as given
+type FileError struct {
+	File string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return e.File + ": " + e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// ScanErrors are the problems found with a set of inputs, one per failing file, in input order
+type ScanErrors []*FileError
+
+func (e ScanErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e ScanErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Files lists the failing files
+func (e ScanErrors) Files() []string {
+	files := make([]string, len(e))
+	for i, err := range e {
+		files[i] = err.File
+	}
+	return files
+}
+
+// Cause is why file failed, nil if it didn't
+func (e ScanErrors) Cause(file string) error {
+	for _, err := range e {
+		if err.File == file {
+			return err.Err
+		}
+	}
+	return nil
+}
+
+// orNil keeps an empty ScanErrors from turning into a non-nil error
+func (e ScanErrors) orNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// ValidateInputs resolves globs and directories into a list of files and checks that each one
+// exists, can be opened and is not empty. Problem files are left out of the returned list and
+// reported in the error, a ScanErrors.
+func ValidateInputs(files ...string) ([]string, error) {
+	return ValidateInputsMax(DefaultMaxFiles, files...)
+}
+
+// ValidateInputsMax is ValidateInputs failing with ErrTooManyFiles, and no files, when the inputs
+// expand to more than maxFiles files. Expanding stops at the first file past the limit, so a huge
+// directory isn't walked through.
+func ValidateInputsMax(maxFiles int, files ...string) ([]string, error) {
+	return validateInputsWith(maxFiles, osOpen, files...)
+}
+
+// validateInputsWith is ValidateInputsMax opening every file with open
+func validateInputsWith(maxFiles int, open fileOpener, files ...string) ([]string, error) {
+	expanded, errs := expandInputs(files, maxFiles)
+	if len(expanded) > maxFiles {
+		return nil, fmt.Errorf("%w: more than %d; narrow the pattern or raise the limit", ErrTooManyFiles, maxFiles)
+	}
+
+	var resolved []string
+	for _, f := range expanded {
+		if f == "stdin" {
+			resolved = append(resolved, f)
+			continue
+		}
+		if err := checkInput(f, open); err != nil {
+			errs = append(errs, &FileError{File: f, Err: err})
+			continue
+		}
+		resolved = append(resolved, f)
+	}
+	return resolved, errs.orNil()
+}
+
+// expandInputs expands files until there are more than maxFiles of them
+func expandInputs(files []string, maxFiles int) ([]string, ScanErrors) {
+	var expanded []string
+	var errs ScanErrors
+	for _, f := range files {
+		if len(expanded) > maxFiles {
+			break
+		}
+		if f == "stdin" {
+			expanded = append(expanded, f)
+			continue
+		}
+		matches := []string{f}
+		if strings.ContainsAny(f, "*?[") {
+			var err error
+			matches, err = filepath.Glob(f)
+			if err != nil {
+				errs = append(errs, &FileError{File: f, Err: fmt.Errorf("bad pattern: %w", err)})
+				continue
+			}
+			if len(matches) == 0 {
+				errs = append(errs, &FileError{File: f, Err: ErrNoMatch})
+				continue
+			}
+		}
+		for _, m := range matches {
+			if len(expanded) > maxFiles {
+				break
+			}
+			files, err := expandDir(m, maxFiles-len(expanded))
+			if err != nil {
+				errs = append(errs, &FileError{File: m, Err: err})
+			}
+			expanded = append(expanded, files...)
+		}
+	}
+	return expanded, errs
+}
+
+// expandDir lists the regular files under path, stopping at the first past limit
+func expandDir(path string, limit int) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		// Let checkInput report anything wrong with it
+		return []string{path}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			files = append(files, p)
+		}
+		if len(files) > limit {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	return files, err
+}
+
+// checkInput returns why f can't be scanned, without naming f
+func checkInput(f string, open fileOpener) error {
+	info, err := os.Stat(f)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrMissingFile
+		}
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return ErrNotRegular
+	}
+	file, err := open(f)
+	if err != nil {
+		return fmt.Errorf("unreadable: %w", err)
+	}
+	file.Close()
+	if info.Size() == 0 {
+		return ErrEmptyFile
+	}
+	return nil
+}
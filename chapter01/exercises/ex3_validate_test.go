@@ -0,0 +1,153 @@
+/**
+	Exercise 1.3: Duplicate Line Counter with Line Numbers [HARD]
+	Input validation
+**/
+
+package exercises
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateInputsMissing(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a.log", "x\n")
+	missing := filepath.Join(dir, "nope.log")
+
+	files, err := ValidateInputs(a, missing)
+	if !reflect.DeepEqual(files, []string{a}) {
+		t.Errorf("files = %v, want [%s]", files, a)
+	}
+	if err == nil || !strings.Contains(err.Error(), missing+": missing") {
+		t.Errorf("err = %v, want it to report %s as missing", err, missing)
+	}
+}
+
+func TestValidateInputsEmpty(t *testing.T) {
+	empty := writeTestFile(t, t.TempDir(), "empty.log", "")
+
+	files, err := ValidateInputs(empty)
+	if len(files) != 0 {
+		t.Errorf("files = %v, want none", files)
+	}
+	if err == nil || !strings.Contains(err.Error(), empty+": empty") {
+		t.Errorf("err = %v, want it to report %s as empty", err, empty)
+	}
+}
+
+func TestValidateInputsDirectoriesAndGlobs(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a.log", "x\n")
+	b := writeTestFile(t, dir, "b.txt", "y\n")
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	c := writeTestFile(t, filepath.Join(dir, "sub"), "c.log", "z\n")
+
+	files, err := ValidateInputs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{a, b, c}; !reflect.DeepEqual(files, want) {
+		t.Errorf("dir: files = %v, want %v", files, want)
+	}
+
+	files, err = ValidateInputs(filepath.Join(dir, "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{a}; !reflect.DeepEqual(files, want) {
+		t.Errorf("glob: files = %v, want %v", files, want)
+	}
+
+	if _, err := ValidateInputs(filepath.Join(dir, "*.gz")); err == nil || !strings.Contains(err.Error(), "no files match") {
+		t.Errorf("err = %v, want a no match error", err)
+	}
+}
+
+func TestValidateInputsUnreadable(t *testing.T) {
+	// Permissions don't stop root, so the open fails as it would for anyone else
+	f := writeTestFile(t, t.TempDir(), "secret.log", "x\n")
+	denied := func(name string) (io.ReadCloser, error) {
+		if name == f {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+		}
+		return os.Open(name)
+	}
+
+	files, err := validateInputsWith(DefaultMaxFiles, denied, f)
+	if len(files) != 0 {
+		t.Errorf("files = %v, want none", files)
+	}
+	if err == nil || !strings.Contains(err.Error(), f+": unreadable") {
+		t.Errorf("err = %v, want it to report %s as unreadable", err, f)
+	}
+}
+
+func TestValidateInputsMaxFiles(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 5 {
+		writeTestFile(t, dir, fmt.Sprintf("%d.log", i), "x\n")
+	}
+
+	files, err := ValidateInputsMax(4, filepath.Join(dir, "*.log"))
+	if !errors.Is(err, ErrTooManyFiles) {
+		t.Fatalf("err = %v, want %v", err, ErrTooManyFiles)
+	}
+	if !strings.Contains(err.Error(), "more than 4") || !strings.Contains(err.Error(), "narrow the pattern") {
+		t.Errorf("err = %q should give the limit and what to do", err)
+	}
+	if len(files) != 0 {
+		t.Errorf("files = %v, want none", files)
+	}
+
+	if files, err := ValidateInputsMax(5, dir); err != nil || len(files) != 5 {
+		t.Errorf("at the limit: files = %v, err = %v", files, err)
+	}
+
+	// The walk stops at the first file past the limit rather than listing the whole tree
+	if files, _ := expandInputs([]string{dir, dir}, 2); len(files) != 3 {
+		t.Errorf("expanded %d files with a limit of 2, want 3", len(files))
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a.log", "x\n")
+	empty := writeTestFile(t, dir, "empty.log", "")
+	missing := filepath.Join(dir, "nope.log")
+	noMatch := filepath.Join(dir, "*.gz")
+
+	files, err := ValidateInputs(a, missing, noMatch, empty)
+	if !reflect.DeepEqual(files, []string{a}) {
+		t.Errorf("files = %v, want [%s]", files, a)
+	}
+	var scanErrs ScanErrors
+	if !errors.As(err, &scanErrs) {
+		t.Fatalf("err = %T %v, want ScanErrors", err, err)
+	}
+	// Pattern errors come from the expansion, before any file is checked
+	if want := []string{noMatch, missing, empty}; !reflect.DeepEqual(scanErrs.Files(), want) {
+		t.Errorf("Files() = %v, want %v", scanErrs.Files(), want)
+	}
+	for file, want := range map[string]error{missing: ErrMissingFile, noMatch: ErrNoMatch, empty: ErrEmptyFile, a: nil} {
+		if got := scanErrs.Cause(file); got != want {
+			t.Errorf("Cause(%s) = %v, want %v", file, got, want)
+		}
+	}
+	if !errors.Is(err, ErrEmptyFile) {
+		t.Error("errors.Is doesn't see the causes")
+	}
+
+	if _, err := ValidateInputs(a); err != nil {
+		t.Errorf("err = %#v for a good file, want nil", err)
+	}
+}
@@ -19,71 +19,416 @@ package exercises
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
-	"syscall"
+	"strings"
 	"sync/atomic"
+	"syscall"
 	"time"
 
-    "github.com/go-chi/chi/v5"
-    "github.com/go-chi/chi/v5/middleware"
-    "github.com/go-chi/httprate"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/httprate"
 )
 
-var counter atomic.Int64
+// Build info served at /version, set at build time with e.g.
+//
+//	go build -ldflags "-X github.com/rvsubbu/donovan-exercises/chapter01/exercises.Version=v1.2.3
+//		-X github.com/rvsubbu/donovan-exercises/chapter01/exercises.Commit=$(git rev-parse HEAD)
+//		-X github.com/rvsubbu/donovan-exercises/chapter01/exercises.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// Counter is the request counter behind /counter. Each router gets its own, so several servers
+// (e.g. in tests) can live in one process without sharing state.
+type Counter struct {
+	n atomic.Int64
+}
+
+func (c *Counter) Inc() int64 {
+	return c.n.Add(1)
+}
+
+func (c *Counter) Add(delta int64) int64 {
+	return c.n.Add(delta)
+}
+
+func (c *Counter) Value() int64 {
+	return c.n.Load()
+}
+
+// setRequestID echoes the ID from middleware.RequestID back to the client, so it can be matched
+// against the server logs
+func setRequestID(w http.ResponseWriter, r *http.Request) string {
+	reqID := middleware.GetReqID(r.Context())
+	w.Header().Set("X-Request-ID", reqID)
+	return reqID
+}
+
+type ServerConfig struct {
+	Addr          string
+	MaxBodyBytes  int64 // request bodies beyond this get a 413
+	MaxConcurrent int   // requests served at once, more get a 503; 0 means no limit
+	// Peers allowed to tell us the client IP via X-Forwarded-For / X-Real-IP,
+	// e.g. the load balancer or istio sidecar in front of us
+	TrustedProxies []netip.Prefix
+	// Zero means no timeout, which leaves the server open to Slowloris style attacks
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	Logger *slog.Logger // nil logs to slog.Default()
+	// Layout of the log timestamps, LogTimeRFC3339, LogTimeEpochMillis or any time layout;
+	// "" leaves them to Logger
+	LogTimeFormat string
+	// Requests taking longer than this are logged as slow, 0 turns it off
+	SlowRequestThreshold time.Duration
+	// Number of client IPs tracked for /stats/ips
+	IPStatsSize int
+	// How long shutdown waits for in-flight requests
+	ShutdownTimeout time.Duration
+	// Repeats of an Idempotency-Key within the TTL don't increment /counter again
+	// 0 means the defaults; a negative IdempotencyKeys turns replays off
+	IdempotencyTTL  time.Duration
+	IdempotencyKeys int // max keys remembered
+	// Readers and hash workers of duplicate scans run by the server
+	ScanParallelism Parallelism
+	ScanJobs        int // max scan jobs remembered, running or finished
+	// Directory POST /scan may read files under, relative paths being taken from it; "" refuses
+	// every scan, as the report shows the lines of the files to any client
+	ScanRoot     string
+	ScanMaxFiles int // files a scan may expand to, more get a 400
+	// Rate limit and log level, which Reload replaces on SIGHUP; nil Reload leaves SIGHUP alone
+	Runtime RuntimeConfig
+	Reload  ConfigProvider
+	// Backs the counter, checked by /readyz; nil keeps it in memory only
+	Store CounterStore
+	// Streaming duplicate counts served at /top; nil answers 404 there
+	Live *LiveCounter
+	// File followed into Live like tail -f by NewChiRouter, with a new LiveCounter when Live is
+	// nil; "" follows none
+	LiveFile string
+	// Mounts net/http/pprof under /debug/pprof. Off by default: profiles show what the server
+	// is doing to anyone who can reach it. A CPU profile longer than WriteTimeout is cut off.
+	EnablePprof bool
+}
+
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		Addr:              ":3333",
+		MaxBodyBytes:      1 << 20,
+		MaxConcurrent:     1000,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+
+		Logger:               slog.Default(),
+		SlowRequestThreshold: time.Second,
+		IPStatsSize:          1024,
+		ShutdownTimeout:      30 * time.Second,
+		IdempotencyTTL:       defaultIdempotencyTTL,
+		IdempotencyKeys:      defaultIdempotencyKeys,
+		ScanJobs:             100,
+		ScanMaxFiles:         1000,
+		Runtime:              DefaultRuntimeConfig(),
+	}
+}
+
+func newHTTPServer(cfg ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
+func clientIPKey(trusted []netip.Prefix) httprate.KeyFunc {
+	return func(r *http.Request) (string, error) {
+		return clientIP(r, trusted), nil
+	}
+}
+
+// clientIP is the direct peer, unless that peer is a trusted proxy. Then X-Forwarded-For is walked
+// right to left, skipping our own proxies, and the first address not in the trusted set is the
+// client; X-Real-IP is the fallback. Headers from untrusted peers are ignored as they can be spoofed.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	if !isTrustedProxy(peer, trusted) {
+		return peer
+	}
+
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if _, err := netip.ParseAddr(hop); err != nil {
+				break
+			}
+			if i == 0 || !isTrustedProxy(hop, trusted) {
+				return hop
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		if _, err := netip.ParseAddr(realIP); err == nil {
+			return realIP
+		}
+	}
+	return peer
+}
+
+func isTrustedProxy(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	for _, prefix := range trusted {
+		if prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// server holds the per instance state shared by the router and the shutdown path
+type server struct {
+	cfg     ServerConfig
+	counter *Counter
+	stats   *ipStats
+	active  *activeRequests
+	idem    *idempotencyCache
+	scans   *scanJobs
+	// The counter started from the value in cfg.Store, so saving it can't lose a count
+	counterLoaded bool
+	// cfg.Logger, filtered by the reloadable log level, with times in cfg.LogTimeFormat
+	logger   *slog.Logger
+	logLevel *slog.LevelVar
+	limiter  atomic.Pointer[rateLimiter]
+	// Clock of the rate limit windows, replaced in tests
+	rateClock func() time.Time
+}
+
+func newServer(cfg ServerConfig) *server {
+	s := &server{
+		cfg:      cfg,
+		counter:  &Counter{},
+		stats:    newIPStats(cfg.IPStatsSize),
+		active:   &activeRequests{},
+		idem:     newIdempotencyCache(cfg.IdempotencyTTL, cfg.IdempotencyKeys),
+		scans:    newScanJobs(cfg.ScanJobs),
+		logLevel: &slog.LevelVar{},
+
+		rateClock: time.Now,
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	s.logger = slog.New(levelFilter{s.logLevel, withTimeFormat(logger.Handler(), cfg.LogTimeFormat)})
+	s.applyRuntime(cfg.Runtime)
+	s.loadCounter()
+	return s
+}
 
-func NewChiRouter() {
-		r := chi.NewRouter()
+func writeJSONError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":  message,
+		"method": r.Method,
+		"path":   r.URL.Path,
+	})
+}
+
+func BuildRouter(cfg ServerConfig) http.Handler {
+	return newServer(cfg).routes()
+}
+
+func (s *server) routes() http.Handler {
+	cfg, counter, stats := s.cfg, s.counter, s.stats
+
+	r := chi.NewRouter()
+	r.Use(s.active.middleware)
+
+	// Orchestrators poll /health often, and it only says the process is up: it skips the request
+	// ID, logging, load and rate limits of the other routes, so it costs an atomic add and is
+	// never answered 429 or 503
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("OK\n"))
+	})
+
+	r.Group(func(r chi.Router) {
 		r.Use(middleware.RequestID)
-		r.Use(middleware.Logger)
-		r.Use(middleware.Recoverer)
-		r.Use(httprate.LimitByIP(10, time.Minute))
+		r.Use(requestLogger(s.logger))
+		if cfg.SlowRequestThreshold > 0 {
+			r.Use(slowRequestLogger(s.logger, cfg.SlowRequestThreshold))
+		}
+		r.Use(jsonRecoverer(s.logger))
+		if cfg.MaxConcurrent > 0 {
+			r.Use(maxInFlight(cfg.MaxConcurrent))
+		}
+		r.Use(stats.middleware(cfg.TrustedProxies))
+		r.Use(s.rateLimit)
+
+		r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+			writeJSONError(w, r, http.StatusNotFound, "not found")
+		})
+		r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+			writeJSONError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		})
 
 		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			setRequestID(w, r)
 			w.Header().Set("Content-Type", "text/plain")
 			w.Write([]byte("hello world\n"))
 		})
 
 		r.Get("/counter", func(w http.ResponseWriter, r *http.Request) {
-			val := counter.Add(1)
+			var val int64
+			if key := r.Header.Get("Idempotency-Key"); key != "" {
+				var replayed bool
+				if val, replayed = s.idem.do(key, counter.Inc); replayed {
+					w.Header().Set("Idempotent-Replayed", "true")
+				}
+			} else {
+				val = counter.Inc()
+			}
+			reqID := setRequestID(w, r)
+			writeCounter(w, r, val, reqID)
+		})
+
+		r.Post("/counter/increment", func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Delta *int64 `json:"delta"`
+			}
+			dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, cfg.MaxBodyBytes))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&body); err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					writeJSONError(w, r, http.StatusRequestEntityTooLarge, "request body too large")
+					return
+				}
+				writeJSONError(w, r, http.StatusBadRequest, `body must be {"delta": <integer>}`)
+				return
+			}
+			if body.Delta == nil {
+				writeJSONError(w, r, http.StatusBadRequest, "missing delta")
+				return
+			}
+			val := counter.Add(*body.Delta)
 			w.Header().Set("Content-Type", "application/json")
 			w.Write([]byte(fmt.Sprintf(`{"count": %d}`, val)))
 		})
 
-		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "text/plain")
-			w.Write([]byte("OK\n"))
+		r.Get("/version", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{
+				"version":    Version,
+				"commit":     Commit,
+				"build_time": BuildTime,
+			})
 		})
 
-		done := make(chan bool)
-		quit := make(chan os.Signal, 1)
-		signal.Notify(quit, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
+		r.Get("/stats/ips", stats.handler)
 
-		go func() {
-			sig := <-quit
-			fmt.Printf("Caught a kill signal %+v, exiting\n", sig)
-			done <- true
-		}()
-
-		server := http.Server{Addr: ":3333", Handler: r}
-		go func() {
-			fmt.Println("Starting server on port 3333")
-			if err := server.ListenAndServe(); err != nil {
-				fmt.Printf("ListenAndServe error %s, exiting\n", err.Error())
-				done <- true
-			}
-		}()
+		r.Post("/scan", s.startScan)
+		r.Get("/scan/{id}", s.scanStatus)
+		r.Get("/metrics", s.scans.metrics.handler)
+		r.Get("/top", s.topDuplicates)
+
+		r.Get("/readyz", s.readyz)
+
+		if cfg.EnablePprof {
+			r.Mount("/debug", middleware.Profiler())
+		}
+	})
+
+	return r
+}
+
+// shutdown stops httpServer, waiting up to ShutdownTimeout for in-flight requests, logs how
+// many of them drained and how many were abandoned, and then saves the counter to the store
+func (s *server) shutdown(httpServer *http.Server) error {
+	inFlight := s.active.count()
+	s.logger.Info("shutting down", "in_flight", inFlight)
 
-		<-done
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30 * time.Second)
-		defer cancel()
+	httpServer.SetKeepAlivesEnabled(false)
+	err := httpServer.Shutdown(ctx)
 
-		server.SetKeepAlivesEnabled(false)
-		if err := server.Shutdown(ctx); err != nil {
-			fmt.Printf("Could not shut down server with error %s\n", err.Error())
+	abandoned := s.active.count()
+	s.logger.Info("shutdown done", "in_flight", inFlight, "drained", max(inFlight-abandoned, 0), "abandoned", abandoned)
+	return errors.Join(err, s.saveCounter())
+}
+
+// NewChiRouter serves cfg, e.g. DefaultServerConfig(), until interrupted
+func NewChiRouter(cfg ServerConfig) {
+	if err := loadRuntime(&cfg); err != nil {
+		fmt.Printf("Error in the server configuration: %s, exiting\n", err)
+		return
+	}
+	if cfg.LiveFile != "" && cfg.Live == nil {
+		cfg.Live = NewLiveCounter(1)
+	}
+	s := newServer(cfg)
+	r := s.routes()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if cfg.LiveFile != "" {
+		go s.followLive(ctx)
+	}
+
+	done := make(chan bool)
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
+
+	if cfg.Reload != nil {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go s.reloadOnSignal(ctx, hup, cfg.Reload)
+	}
+
+	go func() {
+		sig := <-quit
+		fmt.Printf("Caught a kill signal %+v, exiting\n", sig)
+		done <- true
+	}()
+
+	server := newHTTPServer(cfg, r)
+	go func() {
+		fmt.Printf("Starting server on %s\n", cfg.Addr)
+		if err := server.ListenAndServe(); err != nil {
+			fmt.Printf("ListenAndServe error %s, exiting\n", err.Error())
+			done <- true
 		}
-		fmt.Println("Server shutdown")
+	}()
+
+	<-done
+
+	if err := s.shutdown(server); err != nil {
+		fmt.Printf("Could not shut down server with error %s\n", err.Error())
+	}
+	fmt.Println("Server shutdown")
 }
@@ -0,0 +1,109 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Content negotiation
+
+	The client says what it can take in the Accept header, as media ranges with optional weights:
+	"text/plain;q=0.5, application/*". negotiate picks the offer with the highest weight, the
+	most specific matching range deciding an offer's weight (text/plain over text/* over the
+	wildcard for everything), and the server's order breaking ties. No Accept header accepts
+	anything; a weight of 0 refuses.
+**/
+
+package exercises
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+	}
+	return ranges
+}
+
+// negotiate returns the offer (a media type such as "application/json") the request accepts
+// best, "" if it accepts none of them
+func negotiate(r *http.Request, offers ...string) string {
+	header := r.Header.Get("Accept")
+	if header == "" {
+		return offers[0]
+	}
+	ranges := parseAccept(header)
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		typ, subtype, _ := strings.Cut(offer, "/")
+		q, specificity := 0.0, -1
+		for _, mr := range ranges {
+			s := -1
+			switch {
+			case mr.typ == typ && mr.subtype == subtype:
+				s = 2
+			case mr.typ == typ && mr.subtype == "*":
+				s = 1
+			case mr.typ == "*" && mr.subtype == "*":
+				s = 0
+			}
+			if s > specificity {
+				q, specificity = mr.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+type counterBody struct {
+	XMLName   xml.Name `xml:"counter"`
+	Count     int64    `xml:"count"`
+	RequestID string   `xml:"request_id"`
+}
+
+// writeCounter writes the counter as JSON, plain text or XML, as the request accepts
+func writeCounter(w http.ResponseWriter, r *http.Request, val int64, reqID string) {
+	w.Header().Add("Vary", "Accept")
+	switch negotiate(r, "application/json", "text/plain", "application/xml") {
+	case "application/json":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"count": %d, "request_id": %q}`, val, reqID)
+	case "text/plain":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "count: %d\nrequest_id: %s\n", val, reqID)
+	case "application/xml":
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		io.WriteString(w, xml.Header)
+		xml.NewEncoder(w).Encode(counterBody{Count: val, RequestID: reqID})
+	default:
+		writeJSONError(w, r, http.StatusNotAcceptable,
+			"acceptable types: application/json, text/plain, application/xml")
+	}
+}
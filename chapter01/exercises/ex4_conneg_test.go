@@ -0,0 +1,101 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Content negotiation
+**/
+
+package exercises
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"application/json", "text/plain", "application/xml"}
+	for _, tc := range []struct {
+		accept, want string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"text/plain", "text/plain"},
+		{"application/xml", "application/xml"},
+		{"text/*", "text/plain"},
+		{"application/xml;q=0.9, text/plain;q=0.5", "application/xml"},
+		{"text/plain;q=0.5, */*;q=0.1", "text/plain"},
+		{"*/*, application/json;q=0", "text/plain"},
+		{"application/*;q=0.2, application/xml", "application/xml"},
+		{"image/png", ""},
+		{"bogus", ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		if got := negotiate(req, offers...); got != tc.want {
+			t.Errorf("Accept %q: got %q, want %q", tc.accept, got, tc.want)
+		}
+	}
+}
+
+func TestCounterContentTypes(t *testing.T) {
+	h := BuildRouter(DefaultServerConfig())
+	for i, tc := range []struct {
+		accept      string
+		status      int
+		contentType string
+		check       func(body string) error
+	}{
+		{"", http.StatusOK, "application/json", func(body string) error {
+			var v struct{ Count int64 }
+			return json.Unmarshal([]byte(body), &v)
+		}},
+		{"application/json", http.StatusOK, "application/json", func(body string) error {
+			var v struct{ Count int64 }
+			return json.Unmarshal([]byte(body), &v)
+		}},
+		{"text/plain", http.StatusOK, "text/plain", func(body string) error {
+			if !strings.HasPrefix(body, "count: ") {
+				return fmt.Errorf("no count line")
+			}
+			return nil
+		}},
+		{"application/xml", http.StatusOK, "application/xml", func(body string) error {
+			var v counterBody
+			if err := xml.Unmarshal([]byte(body), &v); err != nil {
+				return err
+			}
+			if v.Count == 0 || v.RequestID == "" {
+				return fmt.Errorf("decoded %+v", v)
+			}
+			return nil
+		}},
+		{"image/png", http.StatusNotAcceptable, "application/json", func(body string) error {
+			if !strings.Contains(body, "acceptable types") {
+				return fmt.Errorf("no list of acceptable types")
+			}
+			return nil
+		}},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/counter", nil)
+		req.RemoteAddr = fmt.Sprintf("10.4.1.%d:1234", i)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("Accept %q: status %d, want %d", tc.accept, rec.Code, tc.status)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, tc.contentType) {
+			t.Errorf("Accept %q: Content-Type %q, want %s", tc.accept, ct, tc.contentType)
+		}
+		if err := tc.check(rec.Body.String()); err != nil {
+			t.Errorf("Accept %q: %v in body %q", tc.accept, err, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Idempotent /counter
+
+	Clients that retry (at-least-once delivery) send an Idempotency-Key header; a repeat of a key
+	within the TTL gets the value from the first request instead of incrementing again. Every entry
+	has the same TTL, so insertion order is also expiry order and a FIFO list is enough to expire
+	entries and to evict the oldest ones once the cache is full.
+
+	A ServerConfig built by hand leaves IdempotencyKeys and IdempotencyTTL at 0, which used to
+	turn replays off without a word: nothing fit in the cache, or every entry had expired. 0 now
+	means the default, defaultIdempotencyKeys keys for defaultIdempotencyTTL, and turning replays
+	off takes a negative IdempotencyKeys.
+**/
+
+package exercises
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	defaultIdempotencyKeys = 10000
+	defaultIdempotencyTTL  = 10 * time.Minute
+)
+
+type idempotentEntry struct {
+	key     string
+	value   int64
+	expires time.Time
+}
+
+type idempotencyCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	now     func() time.Time
+	order   *list.List // oldest first, values are *idempotentEntry
+	entries map[string]*list.Element
+}
+
+// newIdempotencyCache remembers size keys for ttl, see above for 0 and a negative size
+func newIdempotencyCache(ttl time.Duration, size int) *idempotencyCache {
+	if ttl == 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	if size == 0 {
+		size = defaultIdempotencyKeys
+	}
+	return &idempotencyCache{
+		ttl:     ttl,
+		size:    size,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// do returns the value stored for key, or runs fn and stores its result. The lock is held across
+// fn so concurrent retries with the same key can't both run it.
+func (c *idempotencyCache) do(key string, fn func() int64) (value int64, replayed bool) {
+	if c.size < 0 {
+		return fn(), false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.expire(now)
+	if e, ok := c.entries[key]; ok {
+		return e.Value.(*idempotentEntry).value, true
+	}
+
+	value = fn()
+	c.entries[key] = c.order.PushBack(&idempotentEntry{key: key, value: value, expires: now.Add(c.ttl)})
+	for c.order.Len() > c.size {
+		c.remove(c.order.Front())
+	}
+	return value, false
+}
+
+func (c *idempotencyCache) expire(now time.Time) {
+	for e := c.order.Front(); e != nil && !now.Before(e.Value.(*idempotentEntry).expires); e = c.order.Front() {
+		c.remove(e)
+	}
+}
+
+func (c *idempotencyCache) remove(e *list.Element) {
+	c.order.Remove(e)
+	delete(c.entries, e.Value.(*idempotentEntry).key)
+}
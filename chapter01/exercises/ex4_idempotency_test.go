@@ -0,0 +1,97 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Idempotent /counter
+**/
+
+package exercises
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCounterIdempotencyKey(t *testing.T) {
+	h := BuildRouter(DefaultServerConfig())
+	get := func(key string) (int64, bool) {
+		req := httptest.NewRequest(http.MethodGet, "/counter", nil)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		var body struct {
+			Count int64 `json:"count"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Count, rec.Header().Get("Idempotent-Replayed") == "true"
+	}
+
+	if n, replayed := get("abc"); n != 1 || replayed {
+		t.Errorf("first abc: count %d, replayed %v", n, replayed)
+	}
+	if n, replayed := get("abc"); n != 1 || !replayed {
+		t.Errorf("retried abc: count %d, replayed %v, want 1 and a replay", n, replayed)
+	}
+	if n, _ := get("def"); n != 2 {
+		t.Errorf("def: count %d, want 2", n)
+	}
+	if n, _ := get(""); n != 3 {
+		t.Errorf("no key: count %d, want 3", n)
+	}
+}
+
+func TestIdempotencyCacheExpiryAndSize(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := newIdempotencyCache(time.Minute, 2)
+	c.now = func() time.Time { return now }
+	var n int64
+	inc := func() int64 { n++; return n }
+
+	c.do("a", inc)
+	now = now.Add(59 * time.Second)
+	if v, replayed := c.do("a", inc); v != 1 || !replayed {
+		t.Errorf("a within TTL: %d, %v", v, replayed)
+	}
+	now = now.Add(time.Second)
+	if v, replayed := c.do("a", inc); v != 2 || replayed {
+		t.Errorf("a after TTL: %d, %v, want a fresh increment", v, replayed)
+	}
+
+	c.do("b", inc)
+	c.do("c", inc) // evicts a, the oldest
+	if _, replayed := c.do("a", inc); replayed {
+		t.Error("a should have been evicted")
+	}
+	if len(c.entries) != 2 || c.order.Len() != 2 {
+		t.Errorf("cache holds %d/%d entries, want 2", len(c.entries), c.order.Len())
+	}
+}
+
+func TestIdempotencyCacheZeroAndOff(t *testing.T) {
+	var n int64
+	inc := func() int64 { n++; return n }
+
+	// A ServerConfig without the idempotency fields still replays
+	c := newIdempotencyCache(0, 0)
+	if c.size != defaultIdempotencyKeys || c.ttl != defaultIdempotencyTTL {
+		t.Errorf("zero config: %d keys for %v, want the defaults", c.size, c.ttl)
+	}
+	c.do("a", inc)
+	if v, replayed := c.do("a", inc); v != 1 || !replayed {
+		t.Errorf("zero config, retried a: %d, %v, want a replay of 1", v, replayed)
+	}
+
+	off := newIdempotencyCache(time.Minute, -1)
+	off.do("a", inc)
+	if v, replayed := off.do("a", inc); v != 3 || replayed {
+		t.Errorf("replays off, retried a: %d, %v, want a fresh increment to 3", v, replayed)
+	}
+	if len(off.entries) != 0 {
+		t.Errorf("replays off: %d keys remembered", len(off.entries))
+	}
+}
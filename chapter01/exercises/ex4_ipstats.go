@@ -0,0 +1,96 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Per client IP request counts
+
+	Counts live in a fixed size LRU, so a flood of unique IPs evicts the least recently seen ones
+	instead of growing the map without bound. Counts of evicted IPs are lost; heavy talkers stay
+	near the front and survive.
+**/
+
+package exercises
+
+import (
+	"container/list"
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+type ipCount struct {
+	IP    string `json:"ip"`
+	Count int64  `json:"count"`
+}
+
+type ipStats struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // front is most recently seen, values are *ipCount
+	entries map[string]*list.Element
+}
+
+func newIPStats(size int) *ipStats {
+	return &ipStats{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (s *ipStats) record(ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[ip]; ok {
+		e.Value.(*ipCount).Count++
+		s.order.MoveToFront(e)
+		return
+	}
+	s.entries[ip] = s.order.PushFront(&ipCount{IP: ip, Count: 1})
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*ipCount).IP)
+	}
+}
+
+// top returns the n busiest IPs, busiest first
+func (s *ipStats) top(n int) []ipCount {
+	s.mu.Lock()
+	counts := make([]ipCount, 0, s.order.Len())
+	for e := s.order.Front(); e != nil; e = e.Next() {
+		counts = append(counts, *e.Value.(*ipCount))
+	}
+	s.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].IP < counts[j].IP
+	})
+	if n < len(counts) {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+func (s *ipStats) middleware(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.record(clientIP(r, trusted))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (s *ipStats) handler(w http.ResponseWriter, r *http.Request) {
+	n := 10
+	if q := r.URL.Query().Get("n"); q != "" {
+		var err error
+		if n, err = strconv.Atoi(q); err != nil || n < 1 {
+			http.Error(w, "n must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.top(n))
+}
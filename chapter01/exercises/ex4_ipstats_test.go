@@ -0,0 +1,55 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Per client IP request counts
+**/
+
+package exercises
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestIPStatsEndpoint(t *testing.T) {
+	h := BuildRouter(DefaultServerConfig())
+	for ip, n := range map[string]int{"192.0.2.1": 3, "192.0.2.2": 1, "192.0.2.3": 5} {
+		for i := 0; i < n; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = ip + ":5555"
+			h.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/ips?n=2", nil)
+	req.RemoteAddr = "192.0.2.9:5555"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	var got []ipCount
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []ipCount{{"192.0.2.3", 5}, {"192.0.2.1", 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestIPStatsEvictsLeastRecentlySeen(t *testing.T) {
+	s := newIPStats(2)
+	s.record("a")
+	s.record("a")
+	s.record("b")
+	s.record("a") // b is now the least recently seen
+	s.record("c")
+
+	want := []ipCount{{"a", 3}, {"c", 1}}
+	if got := s.top(10); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,94 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Log timestamp format
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLogTimeFormat(t *testing.T) {
+	before := time.Now()
+	for _, tc := range []struct {
+		format string
+		check  func(v any) error
+	}{
+		{LogTimeRFC3339, func(v any) error {
+			_, err := time.Parse(time.RFC3339Nano, v.(string))
+			return err
+		}},
+		{LogTimeEpochMillis, func(v any) error {
+			ms := int64(v.(float64))
+			if ms < before.UnixMilli() || ms > time.Now().UnixMilli() {
+				return fmt.Errorf("%d is not now", ms)
+			}
+			return nil
+		}},
+		{"2006-01-02 15:04", func(v any) error {
+			_, err := time.Parse("2006-01-02 15:04", v.(string))
+			return err
+		}},
+	} {
+		var logs bytes.Buffer
+		cfg := DefaultServerConfig()
+		cfg.Logger = slog.New(slog.NewJSONHandler(&logs, nil))
+		cfg.LogTimeFormat = tc.format
+		newServer(cfg).logger.With("component", "test").Info("hello", "n", 1)
+
+		if n := bytes.Count(logs.Bytes(), []byte(`"time"`)); n != 1 {
+			t.Errorf("%s: %d time fields in %q", tc.format, n, logs.String())
+		}
+		var line map[string]any
+		if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+			t.Fatalf("%s: %v in %q", tc.format, err, logs.String())
+		}
+		if err := tc.check(line["time"]); err != nil {
+			t.Errorf("%s: time %v: %v", tc.format, line["time"], err)
+		}
+		if line["msg"] != "hello" || line["component"] != "test" || line["n"] != 1.0 {
+			t.Errorf("%s: lost the record: %q", tc.format, logs.String())
+		}
+	}
+}
+
+func TestRequestLogTimeFormat(t *testing.T) {
+	var logs bytes.Buffer
+	cfg := DefaultServerConfig()
+	cfg.Logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	cfg.LogTimeFormat = LogTimeEpochMillis
+	s := newServer(cfg)
+	h := s.routes()
+	logs.Reset() // anything logged while starting
+
+	before := time.Now().UnixMilli()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/counter", nil))
+	var line map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+		t.Fatalf("%v in %q", err, logs.String())
+	}
+	if ms, ok := line["time"].(float64); !ok || int64(ms) < before || int64(ms) > time.Now().UnixMilli() {
+		t.Errorf("time %v, want epoch millis of now", line["time"])
+	}
+	if line["msg"] != "request" || line["path"] != "/counter" || line["status"] != 200.0 {
+		t.Errorf("request line %q", logs.String())
+	}
+
+	// Reloading the level quiets request logs too
+	rc := DefaultRuntimeConfig()
+	rc.LogLevel = slog.LevelWarn
+	s.applyRuntime(rc)
+	logs.Reset()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/counter", nil))
+	if logs.Len() != 0 {
+		t.Errorf("logged at level warn: %q", logs.String())
+	}
+}
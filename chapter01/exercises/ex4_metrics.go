@@ -0,0 +1,116 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Scan metrics
+
+	Every scan job times itself and counts the lines and bytes it reads, through the progress
+	hook of the detector; the job result carries its duration and throughput. The totals over all
+	jobs, and a histogram of their durations, are exported at /metrics in the Prometheus text
+	format, written by hand as this is all the server exports:
+
+		scan_jobs_total, scan_lines_total, scan_bytes_total    counters
+		scan_duration_seconds                                  histogram
+
+	Bytes are those of the lines as read, decompressed, one newline each.
+**/
+
+package exercises
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// scanStats is how long a scan took and how fast it read
+type scanStats struct {
+	DurationSeconds float64 `json:"duration_seconds"`
+	Lines           int64   `json:"lines"`
+	Bytes           int64   `json:"bytes"`
+	LinesPerSecond  float64 `json:"lines_per_second"`
+	BytesPerSecond  float64 `json:"bytes_per_second"`
+}
+
+// scanMeter counts what a running scan reads, from the concurrent progress events
+type scanMeter struct {
+	start time.Time
+	lines atomic.Int64
+	bytes atomic.Int64
+}
+
+func newScanMeter() *scanMeter {
+	return &scanMeter{start: time.Now()}
+}
+
+func (m *scanMeter) progress(e progressEvent) {
+	m.lines.Add(int64(e.lines))
+	m.bytes.Add(e.bytes)
+}
+
+func (m *scanMeter) stats() scanStats {
+	elapsed := time.Since(m.start).Seconds()
+	st := scanStats{DurationSeconds: elapsed, Lines: m.lines.Load(), Bytes: m.bytes.Load()}
+	if elapsed > 0 {
+		st.LinesPerSecond = float64(st.Lines) / elapsed
+		st.BytesPerSecond = float64(st.Bytes) / elapsed
+	}
+	return st
+}
+
+// Upper bounds of the scan_duration_seconds buckets, +Inf aside
+var scanDurationBuckets = []float64{0.01, 0.1, 1, 10, 60, 600}
+
+// scanMetrics are the totals over all finished scans
+type scanMetrics struct {
+	mu      sync.Mutex
+	jobs    int64
+	lines   int64
+	bytes   int64
+	sum     float64 // seconds
+	buckets []int64 // finished jobs per bucket, not cumulative; the last one is +Inf
+}
+
+func (m *scanMetrics) observe(st scanStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.buckets == nil {
+		m.buckets = make([]int64, len(scanDurationBuckets)+1)
+	}
+	m.jobs++
+	m.lines += st.Lines
+	m.bytes += st.Bytes
+	m.sum += st.DurationSeconds
+	i := 0
+	for i < len(scanDurationBuckets) && st.DurationSeconds > scanDurationBuckets[i] {
+		i++
+	}
+	m.buckets[i]++
+}
+
+func (m *scanMetrics) handler(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprintln(w, "# HELP scan_jobs_total Scan jobs finished.")
+	fmt.Fprintln(w, "# TYPE scan_jobs_total counter")
+	fmt.Fprintf(w, "scan_jobs_total %d\n", m.jobs)
+	fmt.Fprintln(w, "# HELP scan_lines_total Lines read by finished scan jobs.")
+	fmt.Fprintln(w, "# TYPE scan_lines_total counter")
+	fmt.Fprintf(w, "scan_lines_total %d\n", m.lines)
+	fmt.Fprintln(w, "# HELP scan_bytes_total Bytes read by finished scan jobs.")
+	fmt.Fprintln(w, "# TYPE scan_bytes_total counter")
+	fmt.Fprintf(w, "scan_bytes_total %d\n", m.bytes)
+	fmt.Fprintln(w, "# HELP scan_duration_seconds Wall-clock duration of scan jobs.")
+	fmt.Fprintln(w, "# TYPE scan_duration_seconds histogram")
+	var cumulative int64
+	for i, le := range scanDurationBuckets {
+		if m.buckets != nil {
+			cumulative += m.buckets[i]
+		}
+		fmt.Fprintf(w, "scan_duration_seconds_bucket{le=\"%g\"} %d\n", le, cumulative)
+	}
+	fmt.Fprintf(w, "scan_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.jobs)
+	fmt.Fprintf(w, "scan_duration_seconds_sum %g\n", m.sum)
+	fmt.Fprintf(w, "scan_duration_seconds_count %d\n", m.jobs)
+}
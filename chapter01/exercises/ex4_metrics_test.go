@@ -0,0 +1,99 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Scan metrics
+**/
+
+package exercises
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestScanJobStats(t *testing.T) {
+	var content strings.Builder
+	for i := range 5000 {
+		fmt.Fprintf(&content, "line %04d\n", i%100) // 10 bytes a line
+	}
+	dir := t.TempDir()
+	f := writeTestFile(t, dir, "a", content.String())
+	cfg := DefaultServerConfig()
+	cfg.ScanRoot = dir
+	srv := httptest.NewServer(BuildRouter(cfg))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/scan", "application/json", strings.NewReader(fmt.Sprintf(`{"files": [%q]}`, f)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var started scanJob
+	err = json.NewDecoder(resp.Body).Decode(&started)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started.Stats != nil {
+		t.Errorf("running job has stats %+v", started.Stats)
+	}
+
+	st := waitScan(t, srv.URL, started.ID).Stats
+	if st == nil {
+		t.Fatal("finished job has no stats")
+	}
+	if st.Lines != 5000 || st.Bytes != 50000 {
+		t.Errorf("read %d lines, %d bytes; want 5000, 50000", st.Lines, st.Bytes)
+	}
+	if st.DurationSeconds <= 0 {
+		t.Fatalf("duration %v, want > 0", st.DurationSeconds)
+	}
+	if want := float64(st.Lines) / st.DurationSeconds; st.LinesPerSecond != want {
+		t.Errorf("%v lines/s, want %v", st.LinesPerSecond, want)
+	}
+	if ratio := st.BytesPerSecond / st.LinesPerSecond; math.Abs(ratio-10) > 1e-9 {
+		t.Errorf("%v bytes/s, want 10 bytes a line at %v lines/s", st.BytesPerSecond, st.LinesPerSecond)
+	}
+
+	resp, err = http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{
+		"scan_jobs_total 1\n",
+		"scan_lines_total 5000\n",
+		"scan_bytes_total 50000\n",
+		"scan_duration_seconds_bucket{le=\"+Inf\"} 1\n",
+		"scan_duration_seconds_count 1\n",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("/metrics has no %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestScanMetricsBuckets(t *testing.T) {
+	var m scanMetrics
+	for _, d := range []float64{0.005, 0.5, 0.7, 1000} {
+		m.observe(scanStats{DurationSeconds: d})
+	}
+	rec := httptest.NewRecorder()
+	m.handler(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`scan_duration_seconds_bucket{le="0.01"} 1`,
+		`scan_duration_seconds_bucket{le="0.1"} 1`,
+		`scan_duration_seconds_bucket{le="1"} 3`,
+		`scan_duration_seconds_bucket{le="600"} 3`,
+		`scan_duration_seconds_bucket{le="+Inf"} 4`,
+	} {
+		if !strings.Contains(rec.Body.String(), want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,107 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Middleware
+**/
+
+package exercises
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// requestLogger logs every request through logger once served, so request logs follow the
+// level and time format of the server like the rest of its logs
+func requestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			next.ServeHTTP(ww, r)
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK // nothing written
+			}
+			logger.Info("request", "request_id", middleware.GetReqID(r.Context()), "method", r.Method,
+				"path", r.URL.Path, "remote", r.RemoteAddr, "status", status, "bytes", ww.BytesWritten(),
+				"duration", time.Since(start))
+		})
+	}
+}
+
+// slowRequestLogger logs a warning for requests that take longer than threshold. Fast requests
+// only pay for two time.Now calls.
+func slowRequestLogger(logger *slog.Logger, threshold time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			next.ServeHTTP(w, r)
+			if elapsed := time.Since(start); elapsed > threshold {
+				logger.Warn("slow request", "method", r.Method, "path", r.URL.Path, "duration", elapsed)
+			}
+		})
+	}
+}
+
+// activeRequests counts the requests currently being served
+type activeRequests struct {
+	n atomic.Int64
+}
+
+func (a *activeRequests) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.n.Add(1)
+		defer a.n.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *activeRequests) count() int64 {
+	return a.n.Load()
+}
+
+// maxInFlight answers 503 to requests arriving while n others are being served. Unlike the rate
+// limit, which is per client and over time, this bounds the load on the server right now.
+func maxInFlight(n int) func(http.Handler) http.Handler {
+	slots := make(chan struct{}, n)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+				next.ServeHTTP(w, r)
+			default:
+				w.Header().Set("Retry-After", "1")
+				writeJSONError(w, r, http.StatusServiceUnavailable, "server busy")
+			}
+		})
+	}
+}
+
+// jsonRecoverer turns a panic in a handler into a JSON 500 for the client and logs it with its
+// stack for us; the client never sees the stack. http.ErrAbortHandler is passed on, as
+// net/http uses it to abort a response on purpose.
+func jsonRecoverer(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				logger.Error("panic serving request", "method", r.Method, "path", r.URL.Path,
+					"request_id", middleware.GetReqID(r.Context()), "panic", rec, "stack", string(debug.Stack()))
+				writeJSONError(w, r, http.StatusInternalServerError, "internal server error")
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Middleware
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSlowRequestLogger(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	mw := slowRequestLogger(logger, 20*time.Millisecond)
+
+	fast := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	fast.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if logs.Len() != 0 {
+		t.Errorf("fast request logged: %s", logs.String())
+	}
+
+	slow := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+	}))
+	slow.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/slow", nil))
+	for _, want := range []string{"level=WARN", `msg="slow request"`, "method=POST", "path=/slow", "duration="} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log %q does not contain %q", logs.String(), want)
+		}
+	}
+}
+
+func TestSlowRequestLoggerNilLogger(t *testing.T) {
+	cfg := DefaultServerConfig()
+	cfg.Logger, cfg.SlowRequestThreshold = nil, time.Nanosecond
+	rec := httptest.NewRecorder()
+	BuildRouter(cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/counter", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status %d, want 200", rec.Code)
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	const limit = 3
+	started, release := make(chan struct{}), make(chan struct{})
+	h := maxInFlight(limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	var wg sync.WaitGroup
+	for range limit {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("request within the limit: status %d", rec.Code)
+			}
+		}()
+		<-started
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("request over the limit: status %d, Retry-After %q; want 503 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	close(release)
+	wg.Wait()
+	// The slots are free again
+	go func() { <-started }()
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("request after the others finished: status %d", rec.Code)
+	}
+}
+
+func TestJSONRecoverer(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	h := jsonRecoverer(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("nil map somewhere")
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom", nil))
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("status %d, Content-Type %q; want a JSON 500", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %q: %v", rec.Body.String(), err)
+	}
+	if body["error"] != "internal server error" || strings.Contains(rec.Body.String(), "goroutine") {
+		t.Errorf("body %q should be a bare error, no panic or stack", rec.Body.String())
+	}
+	for _, want := range []string{"panic serving request", "nil map somewhere", "path=/boom", "goroutine", "TestJSONRecoverer"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log lacks %q:\n%s", want, logs.String())
+		}
+	}
+}
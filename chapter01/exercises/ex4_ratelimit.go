@@ -0,0 +1,105 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Rate limit windows on an injectable clock
+
+	httprate reads time.Now itself, so on its own a test can only see a window reset by waiting
+	a minute. clockLimitCounter keeps the per-IP counts for httprate but decides which window a
+	request falls in by its own clock, which tests replace. httprate still weighs the previous
+	window by wall-clock time, so after a jump of one window the limit only partly resets; a jump
+	of two windows or more leaves nothing behind and is fully deterministic.
+
+	Every response tells the client its budget, so it can slow down before it hits a 429:
+	X-RateLimit-Limit, X-RateLimit-Remaining, and X-RateLimit-Reset, the Unix time the current
+	window ends. httprate sets the first two, but would compute the reset from time.Now, so the
+	server sets that one itself, by the same clock as the windows.
+**/
+
+package exercises
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-chi/httprate"
+)
+
+const (
+	rateLimitRequests = 10
+	rateLimitWindow   = time.Minute
+)
+
+// The headers httprate sets, all but X-RateLimit-Reset, see rateLimit
+var rateLimitHeaders = httprate.ResponseHeaders{
+	Limit:      "X-RateLimit-Limit",
+	Remaining:  "X-RateLimit-Remaining",
+	Increment:  "X-RateLimit-Increment",
+	RetryAfter: "Retry-After",
+}
+
+// rateLimiter is the limiter of one runtime configuration
+type rateLimiter struct {
+	*httprate.RateLimiter
+	window time.Duration
+}
+
+// reset is the end of the window now is in
+func (l *rateLimiter) reset(now time.Time) time.Time {
+	return now.UTC().Truncate(l.window).Add(l.window)
+}
+
+type clockLimitCounter struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	window   time.Duration
+	start    time.Time      // of the current window
+	current  map[string]int // requests per key in the current window
+	previous map[string]int // and in the one before
+}
+
+var _ httprate.LimitCounter = (*clockLimitCounter)(nil)
+
+func newClockLimitCounter(now func() time.Time) *clockLimitCounter {
+	return &clockLimitCounter{now: now, current: make(map[string]int), previous: make(map[string]int)}
+}
+
+func (c *clockLimitCounter) Config(requestLimit int, windowLength time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.window = windowLength
+}
+
+func (c *clockLimitCounter) Increment(key string, currentWindow time.Time) error {
+	return c.IncrementBy(key, currentWindow, 1)
+}
+
+// The windows passed in by httprate come from time.Now and are ignored, as in Get
+
+func (c *clockLimitCounter) IncrementBy(key string, _ time.Time, amount int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roll()
+	c.current[key] += amount
+	return nil
+}
+
+func (c *clockLimitCounter) Get(key string, _, _ time.Time) (int, int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roll()
+	return c.current[key], c.previous[key], nil
+}
+
+// roll moves the counts along when the clock has entered a new window
+func (c *clockLimitCounter) roll() {
+	start := c.now().UTC().Truncate(c.window)
+	if start.Equal(c.start) {
+		return
+	}
+	if start.Equal(c.start.Add(c.window)) {
+		c.previous = c.current
+	} else {
+		c.previous = make(map[string]int)
+	}
+	c.current = make(map[string]int)
+	c.start = start
+}
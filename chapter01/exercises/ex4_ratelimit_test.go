@@ -0,0 +1,157 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Rate limit windows on an injectable clock
+**/
+
+package exercises
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestRateLimitResetsWithClock(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 30, 0, time.UTC)}
+	s := newServer(DefaultServerConfig())
+	s.rateClock = clock.Now
+	h := s.routes()
+
+	get := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "198.51.100.7:1234"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < rateLimitRequests; i++ {
+		if code := get(); code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, code)
+		}
+	}
+	if code := get(); code != http.StatusTooManyRequests {
+		t.Fatalf("request over the limit: status %d, want 429", code)
+	}
+
+	// Still limited within the window, however long the test takes in real time
+	clock.Advance(20 * time.Second)
+	if code := get(); code != http.StatusTooManyRequests {
+		t.Errorf("later in the window: status %d, want 429", code)
+	}
+
+	// Past the current and the sliding previous window, the budget is whole again
+	clock.Advance(2 * rateLimitWindow)
+	for i := 0; i < rateLimitRequests; i++ {
+		if code := get(); code != http.StatusOK {
+			t.Fatalf("after the window, request %d: status %d", i, code)
+		}
+	}
+	if code := get(); code != http.StatusTooManyRequests {
+		t.Errorf("limit not enforced again: status %d, want 429", code)
+	}
+}
+
+func TestClockLimitCounterRolls(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	c := newClockLimitCounter(clock.Now)
+	c.Config(rateLimitRequests, time.Minute)
+
+	c.IncrementBy("ip", time.Time{}, 3)
+	clock.Advance(time.Minute)
+	c.Increment("ip", time.Time{})
+	if curr, prev, _ := c.Get("ip", time.Time{}, time.Time{}); curr != 1 || prev != 3 {
+		t.Errorf("next window: current %d, previous %d, want 1 and 3", curr, prev)
+	}
+	clock.Advance(2 * time.Minute)
+	if curr, prev, _ := c.Get("ip", time.Time{}, time.Time{}); curr != 0 || prev != 0 {
+		t.Errorf("two windows on: current %d, previous %d, want nothing", curr, prev)
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 30, 0, time.UTC)}
+	s := newServer(DefaultServerConfig())
+	s.rateClock = clock.Now
+	h := s.routes()
+
+	wantReset := strconv.FormatInt(time.Date(2024, 1, 1, 12, 1, 0, 0, time.UTC).Unix(), 10)
+	for i := 0; i <= rateLimitRequests; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "198.51.100.8:1234"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		wantRemaining := strconv.Itoa(max(rateLimitRequests-i-1, 0))
+		if got := rec.Header().Get("X-RateLimit-Limit"); got != strconv.Itoa(rateLimitRequests) {
+			t.Errorf("request %d: X-RateLimit-Limit %q, want %d", i, got, rateLimitRequests)
+		}
+		if got := rec.Header().Get("X-RateLimit-Remaining"); got != wantRemaining {
+			t.Errorf("request %d: X-RateLimit-Remaining %q, want %s", i, got, wantRemaining)
+		}
+		if got := rec.Header().Get("X-RateLimit-Reset"); got != wantReset {
+			t.Errorf("request %d: X-RateLimit-Reset %q, want %s, the end of the window", i, got, wantReset)
+		}
+	}
+
+	clock.Advance(45 * time.Second)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "198.51.100.9:1234"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	reset, err := strconv.ParseInt(rec.Header().Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil || !time.Unix(reset, 0).After(clock.Now()) {
+		t.Errorf("next window: X-RateLimit-Reset %d, %v; want after %v", reset, err, clock.Now())
+	}
+}
+
+func TestHealthNotRateLimited(t *testing.T) {
+	h := BuildRouter(DefaultServerConfig())
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "198.51.100.10:1234"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 10*rateLimitRequests; i++ {
+		rec := get("/health")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("/health request %d: status %d", i, rec.Code)
+		}
+		if rec.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("/health request %d went through the rate limiter", i)
+		}
+	}
+	// The same client is still limited everywhere else
+	for i := 0; i < rateLimitRequests; i++ {
+		get("/")
+	}
+	if code := get("/").Code; code != http.StatusTooManyRequests {
+		t.Errorf("/ after %d requests: status %d, want 429", rateLimitRequests, code)
+	}
+	if code := get("/health").Code; code != http.StatusOK {
+		t.Errorf("/health once limited elsewhere: status %d, want 200", code)
+	}
+}
@@ -0,0 +1,160 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Reloading the configuration on SIGHUP
+
+	The rate limit and the log level can change without a restart: on SIGHUP the server asks its
+	ConfigProvider for a fresh RuntimeConfig and swaps it in atomically. Requests already being
+	served finish under the old settings, later ones get the new. A new rate limit starts from
+	empty windows, so every client gets a clean slate on reload. If the provider fails the old
+	configuration stays and the error is logged.
+**/
+
+package exercises
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/httprate"
+)
+
+// RuntimeConfig is the part of ServerConfig that can be reloaded
+type RuntimeConfig struct {
+	RateLimitRequests int // per client IP and window
+	RateLimitWindow   time.Duration
+	LogLevel          slog.Level
+}
+
+func DefaultRuntimeConfig() RuntimeConfig {
+	return RuntimeConfig{RateLimitRequests: rateLimitRequests, RateLimitWindow: rateLimitWindow, LogLevel: slog.LevelInfo}
+}
+
+// ConfigProvider returns the current runtime configuration, e.g. by reading a file
+type ConfigProvider func() (RuntimeConfig, error)
+
+// RuntimeConfigFile reads the runtime configuration from a JSON file such as
+//
+//	{"rate_limit_requests": 20, "rate_limit_window": "30s", "log_level": "debug"}
+//
+// Settings left out keep their defaults.
+func RuntimeConfigFile(path string) ConfigProvider {
+	return func() (RuntimeConfig, error) {
+		cfg := DefaultRuntimeConfig()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, err
+		}
+		var file struct {
+			RateLimitRequests *int       `json:"rate_limit_requests"`
+			RateLimitWindow   string     `json:"rate_limit_window"`
+			LogLevel          slog.Level `json:"log_level"`
+		}
+		file.LogLevel = cfg.LogLevel
+		if err := json.Unmarshal(data, &file); err != nil {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+		if file.RateLimitRequests != nil {
+			cfg.RateLimitRequests = *file.RateLimitRequests
+		}
+		if file.RateLimitWindow != "" {
+			if cfg.RateLimitWindow, err = time.ParseDuration(file.RateLimitWindow); err != nil {
+				return cfg, fmt.Errorf("%s: rate_limit_window: %w", path, err)
+			}
+		}
+		cfg.LogLevel = file.LogLevel
+		return cfg, nil
+	}
+}
+
+func (c RuntimeConfig) validate() error {
+	if c.RateLimitRequests <= 0 || c.RateLimitWindow <= 0 {
+		return fmt.Errorf("rate limit of %d per %v must be positive", c.RateLimitRequests, c.RateLimitWindow)
+	}
+	return nil
+}
+
+// loadRuntime sets cfg.Runtime from cfg.Reload, so a configuration file applies from the start
+// and not only from the first SIGHUP
+func loadRuntime(cfg *ServerConfig) error {
+	if cfg.Reload == nil {
+		return nil
+	}
+	rc, err := cfg.Reload()
+	if err == nil {
+		err = rc.validate()
+	}
+	if err != nil {
+		return err
+	}
+	cfg.Runtime = rc
+	return nil
+}
+
+// applyRuntime makes rc the configuration of requests from now on
+func (s *server) applyRuntime(rc RuntimeConfig) {
+	s.logLevel.Set(rc.LogLevel)
+	s.limiter.Store(&rateLimiter{
+		RateLimiter: httprate.NewRateLimiter(rc.RateLimitRequests, rc.RateLimitWindow,
+			httprate.WithKeyFuncs(clientIPKey(s.cfg.TrustedProxies)),
+			// rateClock is looked up late, tests replace it after newServer
+			httprate.WithLimitCounter(newClockLimitCounter(func() time.Time { return s.rateClock() })),
+			httprate.WithResponseHeaders(rateLimitHeaders)),
+		window: rc.RateLimitWindow,
+	})
+}
+
+// rateLimit runs every request through whichever limiter is current
+func (s *server) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter := s.limiter.Load()
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(limiter.reset(s.rateClock()).Unix(), 10))
+		limiter.Handler(next).ServeHTTP(w, r)
+	})
+}
+
+// reloadOnSignal reloads the runtime configuration from load on every signal received on sigs,
+// one at a time, until ctx is done
+func (s *server) reloadOnSignal(ctx context.Context, sigs <-chan os.Signal, load ConfigProvider) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-sigs:
+			rc, err := load()
+			if err == nil {
+				err = rc.validate()
+			}
+			if err != nil {
+				s.logger.Error("config reload failed, keeping the old one", "signal", sig, "err", err)
+				continue
+			}
+			s.applyRuntime(rc)
+			s.logger.Info("config reloaded", "signal", sig, "rate_limit", rc.RateLimitRequests,
+				"window", rc.RateLimitWindow, "log_level", rc.LogLevel)
+		}
+	}
+}
+
+// levelFilter drops records below a level that can change while the logger is in use
+type levelFilter struct {
+	level slog.Leveler
+	slog.Handler
+}
+
+func (h levelFilter) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.Handler.Enabled(ctx, level)
+}
+
+func (h levelFilter) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return levelFilter{h.level, h.Handler.WithAttrs(attrs)}
+}
+
+func (h levelFilter) WithGroup(name string) slog.Handler {
+	return levelFilter{h.level, h.Handler.WithGroup(name)}
+}
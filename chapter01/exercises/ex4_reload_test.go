@@ -0,0 +1,162 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Reloading the configuration on SIGHUP
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer the server's logger can write to while the test reads it
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+func TestReloadOnSIGHUP(t *testing.T) {
+	var logs syncBuffer
+	cfg := DefaultServerConfig()
+	cfg.Logger = slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	cfg.Runtime.RateLimitRequests = 2
+	s := newServer(cfg)
+	h := s.routes()
+
+	var mu sync.Mutex
+	next, nextErr := RuntimeConfig{RateLimitRequests: 5, RateLimitWindow: time.Minute, LogLevel: slog.LevelDebug}, error(nil)
+	provider := func() (RuntimeConfig, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return next, nextErr
+	}
+	sigs := make(chan os.Signal)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.reloadOnSignal(ctx, sigs, provider)
+	// sigs is unbuffered and the loop handles one signal at a time, so once a second send goes
+	// through, the reload for the first one is done
+	hup := func() {
+		sigs <- syscall.SIGHUP
+		sigs <- syscall.SIGHUP
+	}
+
+	allowed := func(ip string) int {
+		n := 0
+		for range 10 {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = ip + ":1234"
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code == http.StatusOK {
+				n++
+			}
+		}
+		return n
+	}
+
+	if n := allowed("198.51.100.1"); n != 2 {
+		t.Fatalf("before reload %d requests allowed, want 2", n)
+	}
+	s.logger.Debug("before reload")
+
+	hup()
+	if n := allowed("198.51.100.2"); n != 5 {
+		t.Errorf("after reload %d requests allowed, want 5", n)
+	}
+	s.logger.Debug("after reload")
+	if got := logs.String(); strings.Contains(got, "before reload") || !strings.Contains(got, "after reload") {
+		t.Errorf("log level not reloaded, logs:\n%s", got)
+	}
+
+	// A failing provider leaves the config alone
+	mu.Lock()
+	next, nextErr = RuntimeConfig{}, errors.New("config file gone")
+	mu.Unlock()
+	hup()
+	if n := allowed("198.51.100.3"); n != 5 {
+		t.Errorf("after a failed reload %d requests allowed, want 5", n)
+	}
+	if !strings.Contains(logs.String(), "config file gone") {
+		t.Errorf("failed reload not logged, logs:\n%s", logs.String())
+	}
+}
+
+func TestRuntimeConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "runtime.json")
+	if err := os.WriteFile(path, []byte(`{"rate_limit_requests": 20, "rate_limit_window": "30s", "log_level": "debug"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := RuntimeConfigFile(path)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (RuntimeConfig{RateLimitRequests: 20, RateLimitWindow: 30 * time.Second, LogLevel: slog.LevelDebug}); got != want {
+		t.Errorf("config = %+v, want %+v", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"log_level": "warn"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err = RuntimeConfigFile(path)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultRuntimeConfig()
+	want.LogLevel = slog.LevelWarn
+	if got != want {
+		t.Errorf("partial config = %+v, want %+v", got, want)
+	}
+
+	if _, err := RuntimeConfigFile(filepath.Join(dir, "missing.json"))(); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestLoadRuntime(t *testing.T) {
+	cfg := DefaultServerConfig()
+	if err := loadRuntime(&cfg); err != nil || cfg.Runtime != DefaultRuntimeConfig() {
+		t.Errorf("no Reload: runtime %+v, err %v; want the defaults", cfg.Runtime, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "runtime.json")
+	if err := os.WriteFile(path, []byte(`{"rate_limit_requests": 20}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Reload = RuntimeConfigFile(path)
+	if err := loadRuntime(&cfg); err != nil || cfg.Runtime.RateLimitRequests != 20 {
+		t.Errorf("runtime %+v, err %v; want 20 requests", cfg.Runtime, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"rate_limit_requests": 0}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadRuntime(&cfg); err == nil || cfg.Runtime.RateLimitRequests != 20 {
+		t.Errorf("invalid file: runtime %+v, err %v; want an error and the runtime kept", cfg.Runtime, err)
+	}
+}
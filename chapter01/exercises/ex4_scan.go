@@ -0,0 +1,228 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Duplicate scans as background jobs
+
+	POST /scan {"files": [...], "threshold": N} starts a duplicate line scan (Exercise 1.3) over
+	files on the server and answers 202 with a job ID right away; GET /scan/{id} reports whether
+	it is still running and, once done, the report and how long the scan took. Jobs live in a
+	registry of fixed size, oldest first: a new job evicts the oldest finished one, and if every
+	slot is still running the scan is refused with a 503 rather than queued.
+
+	The report holds the lines of the files, so a scan may only read files under
+	ServerConfig.ScanRoot and is refused with a 403 otherwise, or always when no root is set.
+**/
+
+package exercises
+
+import (
+	"container/list"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type scanStatus string
+
+const (
+	scanRunning scanStatus = "running"
+	scanDone    scanStatus = "done"
+)
+
+type scanJob struct {
+	ID        string           `json:"id"`
+	Status    scanStatus       `json:"status"`
+	Files     []string         `json:"files"`
+	Threshold int              `json:"threshold"`
+	Report    *DuplicateReport `json:"report,omitempty"`
+	Stats     *scanStats       `json:"stats,omitempty"` // once done, see ex4_metrics.go
+}
+
+var (
+	errScanJobsFull    = errors.New("too many scans running")
+	errOutsideScanRoot = errors.New("outside the scan root")
+)
+
+// scanFiles resolves paths under root and expands them into at most maxFiles files. A path
+// outside root is refused before it is expanded, so "/" isn't walked, and a file is refused
+// again once expanded if a symlink leads out of root.
+func scanFiles(root string, maxFiles int, paths []string) ([]string, error) {
+	if root == "" {
+		return nil, fmt.Errorf("%w: scanning is off", errOutsideScanRoot)
+	}
+	root, err := filepath.Abs(root)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan root: %w", err)
+	}
+	var inRoot []string
+	for _, p := range paths {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(root, p)
+		}
+		if !underRoot(root, filepath.Clean(p)) {
+			return nil, fmt.Errorf("%w: %s", errOutsideScanRoot, p)
+		}
+		inRoot = append(inRoot, p)
+	}
+	files, err := ValidateInputsMax(maxFiles, inRoot...)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		real, err := filepath.EvalSymlinks(f)
+		if err != nil {
+			return nil, err
+		}
+		if !underRoot(root, real) {
+			return nil, fmt.Errorf("%w: %s leads to %s", errOutsideScanRoot, f, real)
+		}
+	}
+	return files, nil
+}
+
+func underRoot(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+type scanJobs struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // oldest first, values are *scanJob
+	jobs  map[string]*list.Element
+	// Totals over the finished jobs, for /metrics
+	metrics scanMetrics
+}
+
+func newScanJobs(size int) *scanJobs {
+	return &scanJobs{size: size, order: list.New(), jobs: make(map[string]*list.Element)}
+}
+
+// start registers a job for files and runs the scan in the background
+func (j *scanJobs) start(files []string, threshold int, p Parallelism) (scanJob, error) {
+	job := &scanJob{ID: newScanID(), Status: scanRunning, Files: files, Threshold: threshold}
+
+	j.mu.Lock()
+	if j.order.Len() >= j.size && !j.evictFinished() {
+		j.mu.Unlock()
+		return scanJob{}, errScanJobsFull
+	}
+	j.jobs[job.ID] = j.order.PushBack(job)
+	snapshot := *job
+	j.mu.Unlock()
+
+	go func() {
+		meter := newScanMeter()
+		opts := defaultDupOptions(threshold)
+		p.apply(&opts)
+		opts.progress = meter.progress
+		report := newDuplicateReport(countLines(opts, files...), &opts)
+		stats := meter.stats()
+		j.metrics.observe(stats)
+
+		j.mu.Lock()
+		job.Report, job.Stats, job.Status = report, &stats, scanDone
+		j.mu.Unlock()
+	}()
+	return snapshot, nil
+}
+
+// evictFinished drops the oldest finished job, reporting whether there was one
+func (j *scanJobs) evictFinished() bool {
+	for e := j.order.Front(); e != nil; e = e.Next() {
+		if job := e.Value.(*scanJob); job.Status != scanRunning {
+			j.order.Remove(e)
+			delete(j.jobs, job.ID)
+			return true
+		}
+	}
+	return false
+}
+
+func (j *scanJobs) get(id string) (scanJob, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	e, ok := j.jobs[id]
+	if !ok {
+		return scanJob{}, false
+	}
+	return *e.Value.(*scanJob), true
+}
+
+func newScanID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (s *server) startScan(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Files     []string `json:"files"`
+		Threshold int      `json:"threshold"`
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.cfg.MaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&body); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeJSONError(w, r, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+		writeJSONError(w, r, http.StatusBadRequest, `body must be {"files": [<path>...], "threshold": <integer>}`)
+		return
+	}
+	if len(body.Files) == 0 {
+		writeJSONError(w, r, http.StatusBadRequest, "missing files")
+		return
+	}
+	if err := checkThreshold(body.Threshold); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, f := range body.Files {
+		// The server's stdin is not the client's
+		if f == "stdin" {
+			writeJSONError(w, r, http.StatusBadRequest, "stdin can't be scanned over HTTP")
+			return
+		}
+	}
+	files, err := scanFiles(s.cfg.ScanRoot, s.cfg.ScanMaxFiles, body.Files)
+	if errors.Is(err, errOutsideScanRoot) {
+		writeJSONError(w, r, http.StatusForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, err := s.scans.start(files, body.Threshold, s.cfg.ScanParallelism)
+	if err != nil {
+		writeJSONError(w, r, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/scan/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+func (s *server) scanStatus(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.scans.get(chi.URLParam(r, "id"))
+	if !ok {
+		writeJSONError(w, r, http.StatusNotFound, "no such scan")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
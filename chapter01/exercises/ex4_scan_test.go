@@ -0,0 +1,174 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Duplicate scans as background jobs
+**/
+
+package exercises
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanJob(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a", "x\ny\nx\n")
+	b := writeTestFile(t, dir, "b", "y\nz\nx\n")
+	cfg := DefaultServerConfig()
+	cfg.ScanRoot = dir
+	srv := httptest.NewServer(BuildRouter(cfg))
+	defer srv.Close()
+
+	body := fmt.Sprintf(`{"files": [%q, %q], "threshold": 1}`, a, b)
+	resp, err := http.Post(srv.URL+"/scan", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var started scanJob
+	err = json.NewDecoder(resp.Body).Decode(&started)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusAccepted || started.ID == "" {
+		t.Fatalf("POST /scan: status %d, job %+v", resp.StatusCode, started)
+	}
+	if loc := resp.Header.Get("Location"); loc != "/scan/"+started.ID {
+		t.Errorf("Location = %q, want /scan/%s", loc, started.ID)
+	}
+
+	job := waitScan(t, srv.URL, started.ID)
+
+	want := &DuplicateReport{Threshold: 1, Entries: []DuplicateEntry{
+		{Text: "x", Count: 3, Locations: map[string][]int{a: {1, 3}, b: {3}}},
+		{Text: "y", Count: 2, Locations: map[string][]int{a: {2}, b: {1}}},
+	}}
+	if !reflect.DeepEqual(job.Report, want) {
+		t.Errorf("report = %+v, want %+v", job.Report, want)
+	}
+}
+
+// waitScan polls the scan id until it is done. Polls stay well under the rate limit, a scan in
+// a test takes milliseconds.
+func waitScan(t *testing.T, url, id string) scanJob {
+	t.Helper()
+	var job scanJob
+	for deadline := time.Now().Add(2 * time.Second); job.Status != scanDone; time.Sleep(50 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("scan still %q", job.Status)
+		}
+		resp, err := http.Get(url + "/scan/" + id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&job)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return job
+}
+
+func TestScanJobErrors(t *testing.T) {
+	dir := t.TempDir()
+	f := writeTestFile(t, dir, "a", "x\nx\n")
+	cfg := DefaultServerConfig()
+	cfg.ScanRoot = dir
+	h := BuildRouter(cfg)
+	for i, tc := range []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, "/scan", `{"threshold": 1}`, http.StatusBadRequest},
+		{http.MethodPost, "/scan", `{"files": ["does-not-exist"]}`, http.StatusBadRequest},
+		{http.MethodPost, "/scan", `{"files": ["stdin"]}`, http.StatusBadRequest},
+		{http.MethodPost, "/scan", fmt.Sprintf(`{"files": [%q], "threshold": -1}`, f), http.StatusBadRequest},
+		{http.MethodPost, "/scan", `{"files": [], "depth": 3}`, http.StatusBadRequest},
+		{http.MethodGet, "/scan/unknown", "", http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.RemoteAddr = fmt.Sprintf("10.0.2.%d:1234", i)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s %s %s: status %d, want %d", tc.method, tc.path, tc.body, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestScanJobsEvictFinished(t *testing.T) {
+	jobs := newScanJobs(2)
+	first := &scanJob{ID: "first", Status: scanRunning}
+	second := &scanJob{ID: "second", Status: scanRunning}
+	jobs.jobs[first.ID] = jobs.order.PushBack(first)
+	jobs.jobs[second.ID] = jobs.order.PushBack(second)
+
+	f := writeTestFile(t, t.TempDir(), "a", "x\nx\n")
+	if _, err := jobs.start([]string{f}, 1, Parallelism{}); err != errScanJobsFull {
+		t.Errorf("err = %v, want %v", err, errScanJobsFull)
+	}
+
+	jobs.mu.Lock()
+	second.Status = scanDone
+	jobs.mu.Unlock()
+	if _, err := jobs.start([]string{f}, 1, Parallelism{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := jobs.get("second"); ok {
+		t.Error("finished job was not evicted")
+	}
+	if _, ok := jobs.get("first"); !ok {
+		t.Error("running job was evicted")
+	}
+}
+
+func TestScanRoot(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	writeTestFile(t, root, "a", "x\nx\n")
+	secret := writeTestFile(t, outside, "secret", "password\npassword\n")
+	if err := os.Symlink(secret, filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "many"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		writeTestFile(t, filepath.Join(root, "many"), fmt.Sprintf("f%d", i), "y\n")
+	}
+	cfg := DefaultServerConfig()
+	cfg.ScanMaxFiles = 2
+
+	for i, tc := range []struct {
+		root  string
+		files []string
+		want  int
+	}{
+		{root, []string{"a"}, http.StatusAccepted},
+		{root, []string{secret}, http.StatusForbidden},
+		{root, []string{"../" + filepath.Base(outside) + "/secret"}, http.StatusForbidden},
+		{root, []string{"/"}, http.StatusForbidden},
+		{root, []string{"link"}, http.StatusForbidden},
+		{root, []string{"many"}, http.StatusBadRequest},
+		{"", []string{filepath.Join(root, "a")}, http.StatusForbidden},
+	} {
+		cfg.ScanRoot = tc.root
+		h := BuildRouter(cfg)
+		body, _ := json.Marshal(map[string]any{"files": tc.files})
+		req := httptest.NewRequest(http.MethodPost, "/scan", strings.NewReader(string(body)))
+		req.RemoteAddr = fmt.Sprintf("10.0.3.%d:1234", i)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("root %q, files %v: status %d, want %d: %s", tc.root, tc.files, rec.Code, tc.want, rec.Body)
+		}
+	}
+}
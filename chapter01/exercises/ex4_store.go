@@ -0,0 +1,144 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Readiness and the counter store
+
+	/health only says the process is up. /readyz says it can do its job: when the counter is
+	backed by a store (a file, Redis) the store must answer a cheap probe, otherwise the load
+	balancer should send traffic elsewhere. Without a store the counter lives in memory and the
+	server is always ready.
+
+	The counter starts from the value in the store, so it carries on across restarts. If that
+	can't be read the counter starts from 0 and is not saved, rather than overwrite the store.
+
+	On shutdown the counter is saved to the store, once the in-flight requests have drained so
+	that none of them can increment it after the save. Requests abandoned at ShutdownTimeout are
+	still running then; their increments are lost.
+**/
+
+package exercises
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How long /readyz waits for the store before calling it down
+const readyCheckTimeout = 2 * time.Second
+
+// CounterStore is where the counter is kept beyond the process
+type CounterStore interface {
+	// Check verifies the store is reachable and writable, without changing what it holds
+	Check(ctx context.Context) error
+	// Load returns the value last saved, 0 when there is none
+	Load(ctx context.Context) (int64, error)
+	// Save stores the value of the counter
+	Save(ctx context.Context, value int64) error
+}
+
+// How long the server waits for the store to load or save the counter
+const (
+	storeLoadTimeout = 5 * time.Second
+	storeSaveTimeout = 5 * time.Second
+)
+
+// FileCounterStore keeps the counter in a file
+type FileCounterStore struct {
+	Path string
+}
+
+// Check writes and removes a probe file next to Path, which fails on a full, read-only or
+// missing directory
+func (f FileCounterStore) Check(ctx context.Context) error {
+	probe, err := os.CreateTemp(filepath.Dir(f.Path), ".readyz-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(probe.Name())
+	if _, err := probe.WriteString("ok"); err != nil {
+		probe.Close()
+		return err
+	}
+	return probe.Close()
+}
+
+// Load reads the value from the file, 0 when there is no file yet
+func (f FileCounterStore) Load(ctx context.Context) (int64, error) {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", f.Path, err)
+	}
+	return value, nil
+}
+
+// Save replaces the file with one holding value in decimal, atomically
+func (f FileCounterStore) Save(ctx context.Context, value int64) error {
+	return writeFileAtomic(f.Path, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "%d\n", value)
+		return err
+	})
+}
+
+// loadCounter starts the counter from the store, if there is one
+func (s *server) loadCounter() {
+	if s.cfg.Store == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeLoadTimeout)
+	defer cancel()
+	value, err := s.cfg.Store.Load(ctx)
+	if err != nil {
+		s.logger.Error("loading the counter failed, starting from 0 and not saving it", "err", err)
+		return
+	}
+	s.counter.Add(value)
+	s.counterLoaded = true
+	s.logger.Info("counter loaded", "value", value)
+}
+
+// saveCounter saves the counter to the store, if there is one
+func (s *server) saveCounter() error {
+	if s.cfg.Store == nil {
+		return nil
+	}
+	if !s.counterLoaded {
+		return errors.New("not saving the counter, it didn't start from the stored value")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeSaveTimeout)
+	defer cancel()
+	value := s.counter.Value()
+	if err := s.cfg.Store.Save(ctx, value); err != nil {
+		s.logger.Error("saving the counter failed", "value", value, "err", err)
+		return fmt.Errorf("saving the counter: %w", err)
+	}
+	s.logger.Info("counter saved", "value", value)
+	return nil
+}
+
+func (s *server) readyz(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Store != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout)
+		defer cancel()
+		if err := s.cfg.Store.Check(ctx); err != nil {
+			writeJSONError(w, r, http.StatusServiceUnavailable, "counter store: "+err.Error())
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte("OK\n"))
+}
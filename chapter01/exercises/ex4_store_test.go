@@ -0,0 +1,193 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Readiness and the counter store
+**/
+
+package exercises
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type stubStore struct {
+	err error
+}
+
+func (s stubStore) Check(context.Context) error {
+	return s.err
+}
+
+func (s stubStore) Load(context.Context) (int64, error) {
+	return 0, s.err
+}
+
+func (s stubStore) Save(context.Context, int64) error {
+	return s.err
+}
+
+// recordingStore remembers what it was asked to save
+type recordingStore struct {
+	stubStore
+	mu    sync.Mutex
+	saved []int64
+}
+
+func (s *recordingStore) Save(_ context.Context, value int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved = append(s.saved, value)
+	return s.err
+}
+
+func TestReadyz(t *testing.T) {
+	for i, tc := range []struct {
+		store CounterStore
+		want  int
+		body  string
+	}{
+		{nil, http.StatusOK, "OK"},
+		{stubStore{}, http.StatusOK, "OK"},
+		{stubStore{errors.New("connection refused")}, http.StatusServiceUnavailable, "counter store: connection refused"},
+	} {
+		cfg := DefaultServerConfig()
+		cfg.Store = tc.store
+		req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		rec := httptest.NewRecorder()
+		BuildRouter(cfg).ServeHTTP(rec, req)
+		if rec.Code != tc.want || !strings.Contains(rec.Body.String(), tc.body) {
+			t.Errorf("case %d: status %d, body %q; want %d, %q", i, rec.Code, rec.Body.String(), tc.want, tc.body)
+		}
+	}
+}
+
+func TestFileCounterStoreCheck(t *testing.T) {
+	dir := t.TempDir()
+	store := FileCounterStore{Path: filepath.Join(dir, "counter")}
+	if err := store.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("probe left %d files behind", len(entries))
+	}
+
+	store.Path = filepath.Join(dir, "gone", "counter")
+	if err := store.Check(context.Background()); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestShutdownSavesCounter(t *testing.T) {
+	store := &recordingStore{}
+	cfg := DefaultServerConfig()
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Store = store
+	s := newServer(cfg)
+	s.counter.Add(41)
+
+	// The in-flight request increments the counter while the server drains
+	started := make(chan bool)
+	slow := s.active.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- true
+		time.Sleep(100 * time.Millisecond)
+		s.counter.Inc()
+	}))
+	ts := httptest.NewUnstartedServer(slow)
+	ts.Config = newHTTPServer(cfg, slow)
+	ts.Start()
+	defer ts.Close()
+
+	done := make(chan error)
+	go func() {
+		res, err := http.Get(ts.URL)
+		if err == nil {
+			res.Body.Close()
+		}
+		done <- err
+	}()
+	<-started
+
+	if err := s.shutdown(ts.Config); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(store.saved, []int64{42}) {
+		t.Errorf("saved %v, want [42] once, after the drain", store.saved)
+	}
+
+	store.err = errors.New("disk full")
+	if err := s.saveCounter(); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("failing save: got %v", err)
+	}
+}
+
+func TestFileCounterStoreSave(t *testing.T) {
+	store := FileCounterStore{Path: filepath.Join(t.TempDir(), "counter")}
+	for _, v := range []int64{7, 12} {
+		if err := store.Save(context.Background(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, _ := os.ReadFile(store.Path); string(got) != "12\n" {
+		t.Errorf("file holds %q, want 12", got)
+	}
+}
+
+func TestCounterSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counter")
+	cfg := DefaultServerConfig()
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg.Store = FileCounterStore{Path: path}
+
+	for run, want := range []int64{3, 6} {
+		s := newServer(cfg)
+		h := s.routes()
+		for range 3 {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/counter", nil))
+		}
+		if got := s.counter.Value(); got != want {
+			t.Errorf("run %d: counter %d, want %d", run, got, want)
+		}
+		if err := s.saveCounter(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("garbage\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := newServer(cfg)
+	if err := s.saveCounter(); err == nil {
+		t.Error("saved a counter that didn't start from the stored value")
+	}
+	if got, _ := os.ReadFile(path); string(got) != "garbage\n" {
+		t.Errorf("store overwritten with %q", got)
+	}
+}
+
+func TestFileCounterStoreLoad(t *testing.T) {
+	store := FileCounterStore{Path: filepath.Join(t.TempDir(), "counter")}
+	if value, err := store.Load(context.Background()); value != 0 || err != nil {
+		t.Errorf("missing file: %d, %v; want 0", value, err)
+	}
+	if err := store.Save(context.Background(), 42); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := store.Load(context.Background()); value != 42 || err != nil {
+		t.Errorf("got %d, %v; want 42", value, err)
+	}
+}
@@ -0,0 +1,370 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Difficulty: Hard  **Topic**: HTTP servers, concurrency, rate limiting
+**/
+
+package exercises
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func getCount(t *testing.T, h http.Handler, remoteAddr string) int64 {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/counter", nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /counter: status %d", rec.Code)
+	}
+	var body struct {
+		Count int64 `json:"count"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("GET /counter: %v", err)
+	}
+	return body.Count
+}
+
+func TestRoutersHaveIndependentCounters(t *testing.T) {
+	a, b := BuildRouter(DefaultServerConfig()), BuildRouter(DefaultServerConfig())
+
+	var wg sync.WaitGroup
+	hit := func(h http.Handler, n int) {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			// Spread over IPs so the rate limiter stays out of the way
+			req := httptest.NewRequest(http.MethodGet, "/counter", nil)
+			req.RemoteAddr = fmt.Sprintf("10.0.0.%d:1234", i)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Errorf("GET /counter: status %d", rec.Code)
+			}
+		}
+	}
+	wg.Add(2)
+	go hit(a, 5)
+	go hit(b, 8)
+	wg.Wait()
+
+	if got := getCount(t, a, "10.0.1.1:1234"); got != 6 {
+		t.Errorf("router a count = %d, want 6", got)
+	}
+	if got := getCount(t, b, "10.0.1.1:1234"); got != 9 {
+		t.Errorf("router b count = %d, want 9", got)
+	}
+}
+
+func TestRequestIDHeader(t *testing.T) {
+	var logs bytes.Buffer
+	cfg := DefaultServerConfig()
+	cfg.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	h := BuildRouter(cfg)
+
+	for _, path := range []string{"/", "/counter"} {
+		logs.Reset()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		reqID := rec.Header().Get("X-Request-ID")
+		if reqID == "" {
+			t.Fatalf("GET %s: no X-Request-ID header", path)
+		}
+		if !strings.Contains(logs.String(), "request_id="+reqID+" ") {
+			t.Errorf("GET %s: request ID %q not in log line %q", path, reqID, logs.String())
+		}
+		if path == "/counter" && !strings.Contains(rec.Body.String(), `"request_id": "`+reqID+`"`) {
+			t.Errorf("GET %s: request ID %q not in body %s", path, reqID, rec.Body.String())
+		}
+	}
+}
+
+func TestCounterIncrement(t *testing.T) {
+	cfg := DefaultServerConfig()
+	cfg.MaxBodyBytes = 64
+	h := BuildRouter(cfg)
+
+	for _, tc := range []struct {
+		body   string
+		status int
+		count  int64
+	}{
+		{`{"delta": 5}`, http.StatusOK, 5},
+		{`{"delta": -7}`, http.StatusOK, -2},
+		{`{"delta": 1.5}`, http.StatusBadRequest, 0},
+		{`{"delta": "3"}`, http.StatusBadRequest, 0},
+		{`{"delta": 3`, http.StatusBadRequest, 0},
+		{`{}`, http.StatusBadRequest, 0},
+		{`{"delta": 3, "extra": true}`, http.StatusBadRequest, 0},
+		{`{"delta": 1` + strings.Repeat(" ", 64) + `}`, http.StatusRequestEntityTooLarge, 0},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/counter/increment", strings.NewReader(tc.body))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("POST %s: status %d, want %d", tc.body, rec.Code, tc.status)
+			continue
+		}
+		if tc.status != http.StatusOK {
+			var body struct{ Error string }
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == "" {
+				t.Errorf("POST %s: body %q, want a JSON error", tc.body, rec.Body.String())
+			}
+			continue
+		}
+		if want := fmt.Sprintf(`{"count": %d}`, tc.count); rec.Body.String() != want {
+			t.Errorf("POST %s: body %s, want %s", tc.body, rec.Body.String(), want)
+		}
+	}
+
+	// The rejected requests left the counter alone
+	if got := getCount(t, h, "10.0.1.1:1234"); got != -1 {
+		t.Errorf("count = %d, want -1", got)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	for _, tc := range []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		realIP     string
+		want       string
+	}{
+		{"direct", "203.0.113.5:4000", "", "", "203.0.113.5"},
+		{"spoofed from untrusted peer", "203.0.113.5:4000", "198.51.100.1", "198.51.100.2", "203.0.113.5"},
+		{"trusted proxy", "10.1.2.3:4000", "198.51.100.1", "", "198.51.100.1"},
+		{"proxy chain", "10.1.2.3:4000", "198.51.100.1, 10.9.9.9", "", "198.51.100.1"},
+		{"spoofed hop before real client", "10.1.2.3:4000", "1.2.3.4, 198.51.100.1", "", "198.51.100.1"},
+		{"only proxies", "10.1.2.3:4000", "10.5.5.5, 10.9.9.9", "", "10.5.5.5"},
+		{"real ip", "10.1.2.3:4000", "", "198.51.100.2", "198.51.100.2"},
+		{"garbage header", "10.1.2.3:4000", "not-an-ip", "", "10.1.2.3"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.remoteAddr
+		if tc.xff != "" {
+			req.Header.Set("X-Forwarded-For", tc.xff)
+		}
+		if tc.realIP != "" {
+			req.Header.Set("X-Real-IP", tc.realIP)
+		}
+		if got := clientIP(req, trusted); got != tc.want {
+			t.Errorf("%s: clientIP = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRateLimitBehindProxy(t *testing.T) {
+	cfg := DefaultServerConfig()
+	cfg.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	h := BuildRouter(cfg)
+
+	get := func(remoteAddr, xff string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", xff)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Exhaust one client's budget through the proxy
+	for i := 0; i < 10; i++ {
+		if code := get("10.0.0.1:1234", "198.51.100.1"); code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, code)
+		}
+	}
+	if code := get("10.0.0.1:1234", "198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Errorf("11th request: status %d, want 429", code)
+	}
+	// Another client behind the same proxy has its own bucket
+	if code := get("10.0.0.1:1234", "198.51.100.2"); code != http.StatusOK {
+		t.Errorf("other client: status %d, want 200", code)
+	}
+	// An untrusted peer can't dodge its limit by rotating the header
+	for i := 0; i < 10; i++ {
+		get("203.0.113.9:1234", fmt.Sprintf("198.51.100.%d", 100+i))
+	}
+	if code := get("203.0.113.9:1234", "198.51.100.200"); code != http.StatusTooManyRequests {
+		t.Errorf("spoofing peer: status %d, want 429", code)
+	}
+}
+
+func TestServerTimeouts(t *testing.T) {
+	server := newHTTPServer(DefaultServerConfig(), http.NotFoundHandler())
+	for name, d := range map[string]time.Duration{
+		"ReadHeaderTimeout": server.ReadHeaderTimeout,
+		"ReadTimeout":       server.ReadTimeout,
+		"WriteTimeout":      server.WriteTimeout,
+		"IdleTimeout":       server.IdleTimeout,
+	} {
+		if d <= 0 {
+			t.Errorf("default %s is unbounded", name)
+		}
+	}
+
+	cfg := DefaultServerConfig()
+	cfg.ReadTimeout = 3 * time.Second
+	cfg.WriteTimeout = 4 * time.Second
+	server = newHTTPServer(cfg, http.NotFoundHandler())
+	if server.ReadTimeout != 3*time.Second || server.WriteTimeout != 4*time.Second {
+		t.Errorf("overrides not applied: read %v, write %v", server.ReadTimeout, server.WriteTimeout)
+	}
+	if server.Addr != cfg.Addr {
+		t.Errorf("Addr = %q, want %q", server.Addr, cfg.Addr)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	rec := httptest.NewRecorder()
+	BuildRouter(DefaultServerConfig()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var got map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"version": "dev", "commit": "unknown", "build_time": "unknown"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestPprof(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		cfg := DefaultServerConfig()
+		cfg.EnablePprof = enabled
+		h := BuildRouter(cfg)
+		for _, path := range []string{"/debug/pprof/", "/debug/pprof/goroutine"} {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			want := http.StatusNotFound
+			if enabled {
+				want = http.StatusOK
+			}
+			if rec.Code != want {
+				t.Errorf("pprof enabled %v: %s status %d, want %d", enabled, path, rec.Code, want)
+			}
+		}
+	}
+}
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	var logs bytes.Buffer
+	cfg := DefaultServerConfig()
+	cfg.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	cfg.ShutdownTimeout = 5 * time.Second
+	s := newServer(cfg)
+
+	started := make(chan bool)
+	slow := s.active.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- true
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("done"))
+	}))
+	ts := httptest.NewUnstartedServer(slow)
+	ts.Config = newHTTPServer(cfg, slow)
+	ts.Start()
+	defer ts.Close()
+
+	resp := make(chan string)
+	go func() {
+		res, err := http.Get(ts.URL)
+		if err != nil {
+			resp <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		resp <- string(body)
+	}()
+	<-started
+
+	start := time.Now()
+	if err := s.shutdown(ts.Config); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("shutdown returned after %v, before the request finished", elapsed)
+	}
+	if body := <-resp; body != "done" {
+		t.Errorf("in-flight request got %q, want done", body)
+	}
+	if !strings.Contains(logs.String(), "in_flight=1 drained=1 abandoned=0") {
+		t.Errorf("unexpected shutdown log: %s", logs.String())
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	h := BuildRouter(DefaultServerConfig())
+	for _, tc := range []struct {
+		method, path string
+		status       int
+		message      string
+	}{
+		{http.MethodGet, "/nope", http.StatusNotFound, "not found"},
+		{http.MethodPost, "/health", http.StatusMethodNotAllowed, "method not allowed"},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%s %s: status %d, want %d", tc.method, tc.path, rec.Code, tc.status)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s %s: Content-Type = %q", tc.method, tc.path, ct)
+		}
+		var got map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s %s: %v", tc.method, tc.path, err)
+		}
+		want := map[string]string{"error": tc.message, "method": tc.method, "path": tc.path}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s %s: got %v, want %v", tc.method, tc.path, got, want)
+		}
+	}
+}
+
+func BenchmarkRateLimitedRouter(b *testing.B) {
+	defaultLogger := middleware.DefaultLogger
+	middleware.DefaultLogger = middleware.RequestLogger(&middleware.DefaultLogFormatter{Logger: log.New(io.Discard, "", 0), NoColor: true})
+	defer func() { middleware.DefaultLogger = defaultLogger }()
+	h := BuildRouter(DefaultServerConfig())
+
+	// 4096 simulated clients; once a client is past its 10 requests the limiter answers 429,
+	// which is still a full trip through the in-memory counter
+	var next atomic.Uint32
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			n := next.Add(1) % 4096
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = fmt.Sprintf("10.%d.%d.1:1234", n>>8, n&0xff)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	})
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "req/s")
+}
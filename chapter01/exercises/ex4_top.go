@@ -0,0 +1,47 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Top duplicates of a live scan
+
+	A LiveCounter keeps counting while it follows a file or reads a socket (Exercise 1.3); with
+	one in ServerConfig.Live, GET /top?n=10 returns its current top duplicates, a live log dedup
+	dashboard. NewChiRouter sets one up following ServerConfig.LiveFile. Top takes the counter's
+	lock, so a read never sees a half applied update, and copies the entries out before the lock
+	is released.
+**/
+
+package exercises
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// followLive counts the lines of cfg.LiveFile into cfg.Live as they are written, until ctx is done
+func (s *server) followLive(ctx context.Context) {
+	if err := s.cfg.Live.Follow(ctx, s.cfg.LiveFile, followPoll); err != nil {
+		s.logger.Error("following the live file failed", "file", s.cfg.LiveFile, "err", err)
+	}
+}
+
+func (s *server) topDuplicates(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Live == nil {
+		writeJSONError(w, r, http.StatusNotFound, "no live scan")
+		return
+	}
+	n := 10
+	if q := r.URL.Query().Get("n"); q != "" {
+		var err error
+		if n, err = strconv.Atoi(q); err != nil || n < 1 {
+			writeJSONError(w, r, http.StatusBadRequest, "n must be a positive integer")
+			return
+		}
+	}
+	entries := s.cfg.Live.Top(n)
+	if entries == nil {
+		entries = []DuplicateEntry{} // [] rather than null before the first duplicate
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
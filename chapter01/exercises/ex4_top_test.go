@@ -0,0 +1,101 @@
+/**
+	Exercise 1.4: HTTP Server with Rate Limiting [HARD]
+	Top duplicates of a live scan
+**/
+
+package exercises
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTopEndpoint(t *testing.T) {
+	lc := NewLiveCounter(1)
+	cfg := DefaultServerConfig()
+	cfg.Live = lc
+	h := BuildRouter(cfg)
+
+	top := func(query string) (int, []DuplicateEntry) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/top"+query, nil))
+		var entries []DuplicateEntry
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec.Code, entries
+	}
+
+	if code, entries := top(""); code != http.StatusOK || entries == nil || len(entries) != 0 {
+		t.Fatalf("before any line: status %d, entries %v, want 200 and []", code, entries)
+	}
+
+	feed := func(lines ...string) {
+		client, conn := net.Pipe()
+		done := make(chan bool)
+		go func() {
+			lc.ScanConn(conn)
+			done <- true
+		}()
+		client.Write([]byte(strings.Join(lines, "\n") + "\n"))
+		client.Close()
+		<-done
+	}
+	feed("error: disk full", "ok", "warn: slow", "error: disk full")
+	feed("warn: slow", "error: disk full", "once")
+
+	code, entries := top("?n=2")
+	if code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	want := []DuplicateEntry{{Text: "error: disk full", Count: 3}, {Text: "warn: slow", Count: 2}}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("got %v, want %v", entries, want)
+	}
+
+	feed("warn: slow", "warn: slow")
+	if _, entries := top("?n=1"); !reflect.DeepEqual(entries, []DuplicateEntry{{Text: "warn: slow", Count: 4}}) {
+		t.Errorf("after more lines got %v, want warn: slow 4 times", entries)
+	}
+
+	if code, _ := top("?n=0"); code != http.StatusBadRequest {
+		t.Errorf("n=0: status %d, want 400", code)
+	}
+}
+
+func TestTopEndpointWithoutLiveScan(t *testing.T) {
+	rec := httptest.NewRecorder()
+	BuildRouter(DefaultServerConfig()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/top", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404", rec.Code)
+	}
+}
+
+func TestTopFollowsLiveFile(t *testing.T) {
+	path := writeTestFile(t, t.TempDir(), "app.log", "retry\nok\nretry\n")
+	cfg := DefaultServerConfig()
+	cfg.Live, cfg.LiveFile = NewLiveCounter(1), path
+	s := newServer(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.followLive(ctx)
+
+	want := []DuplicateEntry{{Text: "retry", Count: 2}}
+	deadline := time.Now().Add(5 * time.Second)
+	for got := cfg.Live.Top(10); !reflect.DeepEqual(got, want); got = cfg.Live.Top(10) {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -6,12 +6,78 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"syscall"
+	"time"
 
 	"github.com/rvsubbu/donovan-exercises/chapter01/exercises"
 )
 
+var (
+	checkInputs   = flag.Bool("check", false, "only validate the Exercise 1.3 input files, don't scan them")
+	outFile       = flag.String("o", "", "write the Exercise 1.3 report to this file instead of printing it")
+	outputFormat  = flag.String("format", "text", "format of the -o and -shards reports: text, json, json-pretty, csv, ndjson or dot")
+	jsonPretty    = flag.Bool("json-pretty", false, "indent -format json reports for reading")
+	checkpoint    = flag.String("checkpoint", "", "save the progress of Exercise 1.3 to this file as it scans, and on interrupt")
+	checkpointDur = flag.Duration("checkpoint-every", time.Minute, "least time between two -checkpoint saves")
+	resume        = flag.Bool("resume", false, "carry on the Exercise 1.3 scan saved in -checkpoint, skipping the files done")
+	filesFrom     = flag.String("files-from", "", "scan the Exercise 1.3 files listed in this manifest, one per line")
+	follow        = flag.String("follow", "", "follow this file like tail -f, printing the top duplicates as it grows")
+	parallelFiles = flag.Int("parallel-files", 0, "files read at once by Exercise 1.3, 0 for the number of CPUs")
+	parallelHash  = flag.Int("parallel-hash", 0, "separate hash workers for Exercise 1.3, 0 to hash in the readers")
+	baseline      = flag.String("baseline", "", "only report Exercise 1.3 duplicates that are new or grew since this JSON report")
+	tui           = flag.Bool("tui", false, "browse the Exercise 1.3 report in the terminal instead of printing it")
+	shards        = flag.Int("shards", 0, "split the Exercise 1.3 report by line hash into this many files, in -format")
+	shardDir      = flag.String("shard-dir", ".", "directory for the -shards files")
+	countOnly     = flag.Bool("count-only", false, "print only how many Exercise 1.3 lines are duplicated, keeping no locations")
+	checksum      = flag.Bool("checksum", false, "print only the checksum of the Exercise 1.3 report, equal for equal results")
+	quiet         = flag.Bool("q", false, "print nothing, exit 1 if Exercise 1.3 finds duplicates, 0 if not, 2 on error")
+	summary       = flag.Bool("summary", false, "end Exercise 1.3 with a one line summary on stderr, scanned=N duplicates=M errors=K duration=D; not with -q")
+	charset       = flag.String("charset", "utf-8", "charset of the Exercise 1.3 input files, e.g. latin1 or utf-16le")
+	minFiles      = flag.Int("min-files", 0, "only report Exercise 1.3 lines found in at least this many of the files")
+	sampleRate    = flag.Float64("sample-rate", 1, "count this fraction of the Exercise 1.3 lines and estimate the counts from them")
+	sampleSeed    = flag.Uint64("sample-seed", 1, "seed of the -sample-rate draw, the same seed samples the same lines")
+	byteBudget    = flag.Int64("byte-budget", 0, "stop Exercise 1.3 after scanning this many bytes across the files, 0 for no limit")
+	ignoreRegex   = flag.String("ignore-regex", "", "cut matches of this regexp out of Exercise 1.3 lines before comparing them")
+	groupByFile   = flag.Bool("group-by-file", false, "print the Exercise 1.3 duplicates under each file they occur in")
+	perFile       = flag.Bool("per-file", false, "count each Exercise 1.3 file on its own rather than pooling them")
+	checkCollide  = flag.Bool("check-collisions", false, "warn when two different Exercise 1.3 lines hash to the same key")
+	withOffsets   = flag.Bool("offsets", false, "also report the byte offset of every Exercise 1.3 occurrence")
+	order         = flag.String("order", "count", "report Exercise 1.3 lines by count, text or first-seen")
+	histogram     = flag.Bool("histogram", false, "print how many Exercise 1.3 lines fall in each count bucket rather than the lines")
+	histogramBars = flag.Bool("histogram-bars", false, "draw the -histogram as a bar chart")
+	blockLines    = flag.Int("block", 0, "find Exercise 1.3 blocks of this many consecutive lines repeated, rather than lines")
+	threshold     = flag.Int("threshold", 2, "report Exercise 1.3 lines seen more than this many times")
+	normalizeNums = flag.Bool("normalize-numbers", false, "compare Exercise 1.3 lines with every number replaced by #")
+	redact        = flag.String("redact", "", "report Exercise 1.3 lines redacted: hash or mask")
+	configFile    = flag.String("config", "", "read the Exercise 1.3 options from this JSON file; flags given override it")
+	serverConfig  = flag.String("server-config", "", "read the Exercise 1.4 rate limit and log level from this JSON file, again on SIGHUP")
+	counterFile   = flag.String("counter-file", "", "save the Exercise 1.4 counter to this file on shutdown; /readyz checks it can be written")
+	liveFile      = flag.String("live-file", "", "follow this file like tail -f, serving its top duplicates at Exercise 1.4 /top")
+	scanRoot      = flag.String("scan-root", "", "let Exercise 1.4 POST /scan read the files under this directory, none when empty")
+	maxFiles      = flag.Int("max-files", exercises.DefaultMaxFiles, "refuse Exercise 1.3 inputs expanding to more files than this")
+)
+
+// Exit statuses of -q, as in diff and cmp
+const (
+	exitNoDuplicates = 0
+	exitDuplicates   = 1
+	exitError        = 2
+)
+
 func main() {
+	flag.Parse()
+	if *quiet {
+		os.Exit(quietStatus("a", "b"))
+	}
+
 	fmt.Println("=== Chapter 1, Exercise 1 ===")
 	exercises.Ex1()
 
@@ -21,8 +87,307 @@ func main() {
 	fmt.Println("\n=== Chapter 1, Exercise 3 ===")
 	// exercises.DupDetect(2)
 	// exercises.DupDetectFiles(2, false)
-	exercises.DupDetectFiles(2, false, "a", "b")
+	if *checkInputs {
+		files, err := exercises.ValidateInputsMax(*maxFiles, "a", "b", "sorteda")
+		fmt.Printf("Valid inputs: %v\n", files)
+		var scanErrs exercises.ScanErrors
+		if errors.As(err, &scanErrs) {
+			fmt.Println("Invalid inputs:")
+			for _, file := range scanErrs.Files() {
+				fmt.Printf("\t%s: %s\n", file, scanErrs.Cause(file))
+			}
+		} else if err != nil {
+			fmt.Printf("Invalid inputs:\n%s\n", err)
+		}
+		return
+	}
+	if *follow != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := exercises.FollowFile(ctx, 1, *follow, 5*time.Second); err != nil {
+			fmt.Printf("Error in following %s: %s\n", *follow, err)
+		}
+		return
+	}
+	options, err := detectOptions()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	if *filesFrom != "" {
+		files, err := exercises.ReadManifest(*filesFrom)
+		if err == nil {
+			err = checkMaxFiles(files...)
+		}
+		if err != nil {
+			fmt.Printf("Error in reading manifest %s: %s\n", *filesFrom, err)
+			return
+		}
+		if len(files) == 0 {
+			fmt.Printf("Manifest %s lists no files\n", *filesFrom)
+			return
+		}
+		exercises.Detect(files, options...)
+		return
+	}
+	if err := checkMaxFiles("a", "b", "sorteda"); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	if *checkpoint != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		cp := exercises.Checkpoint{Path: *checkpoint, Every: *checkpointDur, Resume: *resume}
+		report, err := exercises.DetectCheckpointed(ctx, []string{"a", "b"}, cp, options...)
+		if err != nil {
+			fmt.Printf("Scan stopped, -resume carries on from %s: %s\n", *checkpoint, err)
+			return
+		}
+		report.Write(os.Stdout, exercises.FormatText)
+		return
+	}
+	if *baseline != "" {
+		report, err := detectSince(*baseline, options)
+		if err != nil {
+			fmt.Printf("Error in comparing with %s: %s\n", *baseline, err)
+			return
+		}
+		report.Write(os.Stdout, exercises.FormatText)
+		return
+	}
+	if *tui {
+		report, err := exercises.DetectReportWith([]string{"a", "b"}, options...)
+		if err == nil {
+			err = exercises.Browse(report)
+		}
+		if err != nil {
+			fmt.Printf("Error in browsing the report: %s\n", err)
+		}
+		return
+	}
+	if *shards > 0 {
+		if err := writeShards(*shardDir, *shards, *outputFormat, options); err != nil {
+			fmt.Printf("Error in writing shards to %s: %s\n", *shardDir, err)
+		}
+		return
+	}
+	if *outFile != "" {
+		if err := writeReport(*outFile, *outputFormat, options); err != nil {
+			fmt.Printf("Error in writing %s: %s\n", *outFile, err)
+		}
+		return
+	}
+	if *checksum {
+		report, err := exercises.DetectReportWith([]string{"a", "b"}, options...)
+		if err != nil {
+			fmt.Printf("Error in computing the checksum: %s\n", err)
+			return
+		}
+		fmt.Printf("sha256:%s\n", report.ReportChecksum())
+		return
+	}
+	if *countOnly {
+		exercises.Detect([]string{"a", "b"}, options...)
+		return
+	}
+	exercises.Detect([]string{"a", "b"}, options...)
 	exercises.DupDetectFiles(2, true, "sorteda")
 
-	exercises.NewChiRouter()
+	serverCfg := exercises.DefaultServerConfig()
+	if *serverConfig != "" {
+		serverCfg.Reload = exercises.RuntimeConfigFile(*serverConfig)
+	}
+	serverCfg.LiveFile = *liveFile
+	serverCfg.ScanRoot = *scanRoot
+	if *counterFile != "" {
+		serverCfg.Store = exercises.FileCounterStore{Path: *counterFile}
+	}
+	exercises.NewChiRouter(serverCfg)
+}
+
+func writeReport(path, formatName string, options []exercises.DetectOption) error {
+	format, err := parseFormat(formatName)
+	if err != nil {
+		return err
+	}
+	report, err := exercises.DetectReportWith([]string{"a", "b"}, options...)
+	if err != nil {
+		return err
+	}
+	return exercises.WriteReport(path, report, format)
+}
+
+// detectSince is the report of the Exercise 1.3 inputs against the JSON report at baselinePath
+func detectSince(baselinePath string, options []exercises.DetectOption) (*exercises.DuplicateReport, error) {
+	baseline, err := exercises.ReadReport(baselinePath)
+	if err != nil {
+		return nil, err
+	}
+	report, err := exercises.DetectReportWith([]string{"a", "b"}, options...)
+	if err != nil {
+		return nil, err
+	}
+	return report.Since(baseline), nil
+}
+
+// parseFormat is the -format named, indented for -json-pretty
+func parseFormat(name string) (exercises.OutputFormat, error) {
+	format, err := exercises.ParseOutputFormat(name)
+	if format == exercises.FormatJSON && *jsonPretty {
+		format = exercises.FormatPrettyJSON
+	}
+	return format, err
+}
+
+func writeShards(dir string, shards int, formatName string, options []exercises.DetectOption) error {
+	format, err := parseFormat(formatName)
+	if err != nil {
+		return err
+	}
+	report, err := exercises.DetectReportWith([]string{"a", "b"}, options...)
+	if err != nil {
+		return err
+	}
+	_, err = exercises.WriteShards(dir, report, shards, format)
+	return err
+}
+
+// checkMaxFiles refuses Exercise 1.3 inputs expanding to more than -max-files files; other
+// problems with them are left to the scan to report
+func checkMaxFiles(files ...string) error {
+	if _, err := exercises.ValidateInputsMax(*maxFiles, files...); errors.Is(err, exercises.ErrTooManyFiles) {
+		return err
+	}
+	return nil
+}
+
+// quietStatus is the -q exit status for files: whether they have duplicates, or an error when one
+// can't be scanned
+func quietStatus(files ...string) int {
+	files, err := exercises.ValidateInputsMax(*maxFiles, files...)
+	if err != nil {
+		return exitError
+	}
+	options, err := detectOptions()
+	if err != nil {
+		return exitError
+	}
+	found, err := exercises.HasDuplicates(files, append(options, exercises.WithCountOnly(true))...)
+	switch {
+	case err != nil:
+		return exitError
+	case found:
+		return exitDuplicates
+	}
+	return exitNoDuplicates
+}
+
+// detectOptions layers the Exercise 1.3 options: flag defaults, then the -config file, then the
+// flags given on the command line
+func detectOptions() ([]exercises.DetectOption, error) {
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	options, err := flagOptions(func(name string) bool { return !given[name] })
+	if err != nil {
+		return nil, err
+	}
+	if *configFile != "" {
+		fileOptions, err := exercises.DetectConfigFile(*configFile)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, fileOptions...)
+	}
+	givenOptions, err := flagOptions(func(name string) bool { return given[name] })
+	return append(options, givenOptions...), err
+}
+
+// flagOptions returns the options of the flags use selects
+func flagOptions(use func(name string) bool) ([]exercises.DetectOption, error) {
+	var options []exercises.DetectOption
+	if use("threshold") {
+		options = append(options, exercises.WithThreshold(*threshold))
+	}
+	if use("charset") {
+		enc, err := exercises.ParseCharset(*charset)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, exercises.WithCharset(enc))
+	}
+	if use("normalize-numbers") {
+		options = append(options, exercises.WithNormalizeNumbers(*normalizeNums))
+	}
+	if use("redact") {
+		var fn func(string) string // -redact= turns off redaction from -config
+		if *redact != "" {
+			var err error
+			if fn, err = exercises.ParseRedact(*redact); err != nil {
+				return nil, err
+			}
+		}
+		options = append(options, exercises.WithRedact(fn))
+	}
+	if use("ignore-regex") {
+		var re *regexp.Regexp
+		if *ignoreRegex != "" {
+			var err error
+			if re, err = regexp.Compile(*ignoreRegex); err != nil {
+				return nil, fmt.Errorf("-ignore-regex: %w", err)
+			}
+		}
+		options = append(options, exercises.WithIgnoreRegex(re))
+	}
+	if use("min-files") {
+		options = append(options, exercises.WithMinFiles(*minFiles))
+	}
+	if use("summary") {
+		var w io.Writer // -summary=false turns off a summary from -config
+		if *summary {
+			w = os.Stderr
+		}
+		options = append(options, exercises.WithSummary(w))
+	}
+	if use("count-only") {
+		options = append(options, exercises.WithCountOnly(*countOnly))
+	}
+	if use("sample-rate") {
+		options = append(options, exercises.WithSampleRate(*sampleRate))
+	}
+	if use("sample-seed") {
+		options = append(options, exercises.WithSampleSeed(*sampleSeed))
+	}
+	if use("byte-budget") {
+		options = append(options, exercises.WithByteBudget(*byteBudget))
+	}
+	if use("group-by-file") {
+		options = append(options, exercises.WithGroupByFile(*groupByFile))
+	}
+	if use("per-file") {
+		options = append(options, exercises.WithPerFile(*perFile))
+	}
+	if use("check-collisions") {
+		options = append(options, exercises.WithCollisionCheck(*checkCollide))
+	}
+	if use("offsets") {
+		options = append(options, exercises.WithOffsets(*withOffsets))
+	}
+	if use("order") {
+		lineOrder, err := exercises.ParseLineOrder(*order)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, exercises.WithOrder(lineOrder))
+	}
+	if use("histogram") && *histogram {
+		options = append(options, exercises.WithHistogram(nil, *histogramBars))
+	}
+	if use("block") {
+		options = append(options, exercises.WithBlockLines(*blockLines))
+	}
+	if use("parallel-files") || use("parallel-hash") {
+		options = append(options, exercises.WithParallelism(exercises.Parallelism{Files: *parallelFiles, Hash: *parallelHash}))
+	}
+	return options, nil
 }